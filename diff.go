@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/diff"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+)
+
+// diffCommand compares the decisions made for a set of branches under the
+// configuration at two revisions, or in two config files
+func diffCommand(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	repoPath := fs.String("repo", ".", "Path to Git repository")
+	configPath := fs.String("config", "", "Path of the config file in the repository (default: the first of .branchci.yml, .branchci.yaml, .github/branchci.yml, .github/branchci.yaml)")
+	base := fs.String("base", "", "Revision with the original config, e.g. origin/main (required unless -base-config is set)")
+	head := fs.String("head", "HEAD", "Revision with the changed config")
+	baseConfig := fs.String("base-config", "", "Read the original config from this file instead of -base")
+	headConfig := fs.String("head-config", "", "Read the changed config from this file instead of -head")
+	branches := fs.String("branches", "", "Comma-separated branches to evaluate (default: the repository's branches)")
+	outputFormat := fs.String("format", "human", "Output format (human, json, markdown)")
+
+	fs.Parse(args)
+
+	// Positional arguments are accepted as the revisions
+	if *base == "" && fs.NArg() > 0 {
+		*base = fs.Arg(0)
+	}
+	if fs.NArg() > 1 {
+		*head = fs.Arg(1)
+	}
+
+	detector := git.NewDetector(*repoPath)
+	before, baseLabel, err := diffConfig(detector, *base, *baseConfig, *configPath)
+	if err != nil {
+		return err
+	}
+	after, headLabel, err := diffConfig(detector, *head, *headConfig, *configPath)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, name := range strings.Split(*branches, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		if names, err = detector.ListBranches(); err != nil {
+			return err
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("no branches to evaluate; pass -branches")
+	}
+
+	changes, err := diff.Compare(before, after, names, time.Now())
+	if err != nil {
+		return err
+	}
+
+	result, err := diff.Format(&diff.Report{Base: baseLabel, Head: headLabel, Branches: changes}, *outputFormat)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	fmt.Println(result)
+	return nil
+}
+
+// diffConfig loads one side of a diff from a config file when file is set,
+// otherwise from the repository at revision without checking it out. It
+// returns the config and a label naming where it came from. A revision
+// without a config file uses the default configuration, like the CLI does.
+func diffConfig(detector *git.Detector, revision, file, configPath string) (*config.Config, string, error) {
+	if file != "" {
+		cfg, err := config.LoadConfig(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load config: %w", err)
+		}
+		return cfg, file, nil
+	}
+	if revision == "" {
+		return nil, "", errors.New("-base or -base-config is required")
+	}
+
+	paths := config.ConfigPaths
+	if configPath != "" {
+		paths = []string{configPath}
+	}
+	_, data, err := detector.ReadFile(revision, paths...)
+	if errors.Is(err, git.ErrFileNotFound) {
+		return config.DefaultConfig(), revision, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	cfg, err := config.ParseConfig(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", revision, err)
+	}
+	return cfg, revision, nil
+}
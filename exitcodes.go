@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+// Exit codes reported by the CLI. Codes 10-19 identify the error-level finding
+// that failed an enforced run; they are part of the public interface.
+const (
+	exitOK               = 0
+	exitError            = 1  // Invalid usage, configuration or evaluation failure
+	exitBlockedBranch    = 10 // Branch matches policies.blocked_branch_patterns
+	exitBranchNotAllowed = 11 // Branch is not in the environment's allowed_branches
+	exitFreezeWindow     = 12 // A freeze window is active for the environment
+	exitRuleBlocked      = 13 // A policy rule blocked the deployment
+	exitPromotion        = 14 // The commit was not promoted through the previous environment
+	exitPlugin           = 15 // A policy plugin blocked the deployment or failed closed
+	exitRegoDenied       = 16 // A Rego policy denied the deployment
+	exitPolicyError      = 19 // Any other error-level finding
+	exitPolicyWarning    = 20 // A warn-level finding with -fail-on=warn
+)
+
+// findingExitCodes maps error-level finding codes to exit codes
+var findingExitCodes = map[string]int{
+	policy.FindingBlockedBranch:       exitBlockedBranch,
+	policy.FindingBranchNotAllowed:    exitBranchNotAllowed,
+	policy.FindingFreezeWindow:        exitFreezeWindow,
+	policy.FindingRuleBlocked:         exitRuleBlocked,
+	policy.FindingPromotionIneligible: exitPromotion,
+	policy.FindingPluginBlocked:       exitPlugin,
+	policy.FindingPluginFailed:        exitPlugin,
+	policy.FindingRegoDenied:          exitRegoDenied,
+}
+
+// policyFailure is returned when an enforced run has a finding at or above
+// the failure threshold
+type policyFailure struct {
+	finding policy.Finding
+}
+
+func (p *policyFailure) Error() string {
+	return fmt.Sprintf("policy check failed (%s %s): %s", p.finding.Severity, p.finding.Code, p.finding.Message)
+}
+
+// exitCode returns the documented exit code for the finding
+func (p *policyFailure) exitCode() int {
+	if p.finding.Severity != policy.SeverityError {
+		return exitPolicyWarning
+	}
+	if code, ok := findingExitCodes[p.finding.Code]; ok {
+		return code
+	}
+	return exitPolicyError
+}
+
+// exitCode returns the process exit code for an error returned by a command
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var failure *policyFailure
+	if errors.As(err, &failure) {
+		return failure.exitCode()
+	}
+	return exitError
+}
+
+// failOnThreshold resolves the -fail-on and -enforce flags and the config's
+// enforce setting into a severity threshold; an empty result never fails
+func failOnThreshold(failOn string, enforce bool) (policy.Severity, error) {
+	if failOn != "" {
+		severity, err := policy.ParseSeverity(failOn)
+		if err != nil || severity == policy.SeverityInfo {
+			return "", fmt.Errorf("invalid -fail-on value %q (expected warn or error)", failOn)
+		}
+		return severity, nil
+	}
+	if enforce {
+		return policy.SeverityError, nil
+	}
+	return "", nil
+}
+
+// enforceDecision returns a policyFailure if the decision has a finding at or
+// above the threshold
+func enforceDecision(decision *policy.Decision, threshold policy.Severity) error {
+	if threshold == "" {
+		return nil
+	}
+	if finding := decision.FirstFinding(threshold); finding != nil {
+		return &policyFailure{finding: *finding}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+)
+
+// explainCommand evaluates a branch and prints how the decision was reached
+func explainCommand(args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: .branchci.yml)")
+	outputFormat := fs.String("format", "human", "Output format (json, yaml, human)")
+	repoPath := fs.String("repo", ".", "Path to Git repository")
+	branch := fs.String("branch", "", "Evaluate this branch name instead of the checked-out branch")
+	engine := fs.String("engine", "builtin", "Policy engine (builtin, rego)")
+	regoPolicy := fs.String("rego", "", "Rego policy file or bundle directory for -engine rego")
+
+	fs.Parse(args)
+
+	// A positional argument is accepted as the branch name
+	if *branch == "" && fs.NArg() > 0 {
+		*branch = fs.Arg(0)
+	}
+
+	return run(runOptions{
+		repoPath:      *repoPath,
+		configPath:    *configPath,
+		outputFormats: []string{*outputFormat},
+		branch:        *branch,
+		explain:       true,
+		engine:        *engine,
+		regoPolicy:    *regoPolicy,
+	})
+}
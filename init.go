@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+)
+
+// initOptions holds the -init flags
+type initOptions struct {
+	configPath   string
+	repoPath     string
+	preset       string
+	environments string
+}
+
+func initializeConfig(opts initOptions) error {
+	configPath := opts.configPath
+	if configPath == "" {
+		configPath = ".branchci.yml"
+	}
+
+	// Check if file already exists
+	if _, err := os.Stat(configPath); err == nil {
+		return fmt.Errorf("config file already exists: %s", configPath)
+	}
+
+	// Inspect the repository; a missing repository is not fatal
+	branches, err := git.NewDetector(opts.repoPath).ListBranches()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not inspect repository branches: %v\n", err)
+	}
+	mainBranch := config.DetectMainBranch(branches)
+	preset := config.SuggestPreset(branches)
+	environments := splitList(opts.environments)
+
+	if opts.preset != "" {
+		if preset, err = config.ParsePreset(opts.preset); err != nil {
+			return err
+		}
+	} else if isInteractive() {
+		preset, environments, err = promptInit(os.Stdin, os.Stdout, branches, mainBranch, preset, environments)
+		if err != nil {
+			return err
+		}
+	}
+
+	cfg, err := config.NewPresetConfig(preset, config.PresetOptions{
+		MainBranch:   mainBranch,
+		Environments: environments,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Save to file
+	if err := config.SaveConfig(cfg, configPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Generated %s config for primary branch %q\n", preset, mainBranch)
+	return nil
+}
+
+// promptInit asks the user to confirm or override the detected settings
+func promptInit(in io.Reader, out io.Writer, branches []string, mainBranch string,
+	suggested config.Preset, environments []string) (config.Preset, []string, error) {
+	reader := bufio.NewReader(in)
+
+	fmt.Fprintln(out, "🔍 Repository Inspection")
+	fmt.Fprintln(out, "========================")
+	if len(branches) > 0 {
+		fmt.Fprintf(out, "Branches:       %s\n", strings.Join(branches, ", "))
+	} else {
+		fmt.Fprintln(out, "Branches:       (none found)")
+	}
+	fmt.Fprintf(out, "Primary branch: %s\n", mainBranch)
+	fmt.Fprintf(out, "Suggested:      %s\n\n", suggested)
+
+	preset := suggested
+	for {
+		answer, err := ask(reader, out, fmt.Sprintf("Branching model (gitflow, trunk, github-flow) [%s]: ", suggested))
+		if err != nil {
+			return "", nil, err
+		}
+		if answer == "" {
+			break
+		}
+		if preset, err = config.ParsePreset(answer); err == nil {
+			break
+		}
+		fmt.Fprintln(out, err)
+	}
+
+	defaultEnvs := "production,staging,development"
+	if len(environments) > 0 {
+		defaultEnvs = strings.Join(environments, ",")
+	}
+	answer, err := ask(reader, out, fmt.Sprintf("Environments [%s]: ", defaultEnvs))
+	if err != nil {
+		return "", nil, err
+	}
+	if answer != "" {
+		environments = splitList(answer)
+	}
+
+	return preset, environments, nil
+}
+
+// ask prints a prompt and reads a single trimmed line
+func ask(reader *bufio.Reader, out io.Writer, prompt string) (string, error) {
+	fmt.Fprint(out, prompt)
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// isInteractive reports whether stdin is attached to a terminal
+func isInteractive() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/history"
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/output"
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
 )
@@ -15,12 +18,43 @@ var (
 	version = "1.0.0"
 )
 
+// commands maps subcommand names to their implementations
+var commands = map[string]func(args []string) error{
+	"diff":    diffCommand,
+	"explain": explainCommand,
+	"record":  recordCommand,
+	"test":    testCommand,
+}
+
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		if command, exists := commands[os.Args[1]]; exists {
+			err := command(os.Args[2:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(exitCode(err))
+		}
+	}
+
 	// Command-line flags
 	configPath := flag.String("config", "", "Path to config file (default: .branchci.yml)")
-	outputFormat := flag.String("format", "human", "Output format (json, yaml, env, github-env, github-output, human)")
+	var outputFormats, outputs stringList
+	flag.Var(&outputFormats, "format", "Output format (json, yaml, env, shell, github-env, github-output, github-summary, gitlab-dotenv, gitlab-rules, azure, jenkins, buildkite, buildkite-pipeline, circleci, auto, human, plan); repeat or separate with commas for several (default: human)")
+	flag.Var(&outputs, "out", "Write a format to a file instead of stdout, as format=path; repeat or separate with commas for several")
 	repoPath := flag.String("repo", ".", "Path to Git repository")
-	initConfig := flag.Bool("init", false, "Initialize a default config file")
+	branch := flag.String("branch", "", "Evaluate this branch name instead of the checked-out branch")
+	explain := flag.Bool("explain", false, "Include a trace of how the decision was reached")
+	summary := flag.Bool("summary", false, "Also write a GitHub step summary and annotations (github-summary) in addition to -format")
+	deleted := flag.Bool("deleted", false, "Evaluate the branch as deleted, tearing down its ephemeral environments")
+	enforce := flag.Bool("enforce", false, "Exit with a non-zero code when the decision has error-level findings")
+	failOn := flag.String("fail-on", "", "Exit with a non-zero code for findings at or above this severity (warn, error); implies -enforce")
+	engine := flag.String("engine", "builtin", "Policy engine (builtin, rego)")
+	regoPolicy := flag.String("rego", "", "Rego policy file or bundle directory for -engine rego")
+	initConfig := flag.Bool("init", false, "Initialize a config file tailored to the repository")
+	preset := flag.String("preset", "", "Branching model for -init (gitflow, trunk, github-flow); skips prompts")
+	environments := flag.String("environments", "", "Comma-separated environments for -init (default: production,staging,development); other names get an environment without mappings")
 	showVersion := flag.Bool("version", false, "Show version information")
 
 	flag.Parse()
@@ -33,7 +67,13 @@ func main() {
 
 	// Initialize config
 	if *initConfig {
-		if err := initializeConfig(*configPath); err != nil {
+		opts := initOptions{
+			configPath:   *configPath,
+			repoPath:     *repoPath,
+			preset:       *preset,
+			environments: *environments,
+		}
+		if err := initializeConfig(opts); err != nil {
 			fmt.Fprintf(os.Stderr, "Error initializing config: %v\n", err)
 			os.Exit(1)
 		}
@@ -42,61 +82,192 @@ func main() {
 	}
 
 	// Run the main analysis
-	if err := run(*repoPath, *configPath, *outputFormat); err != nil {
+	opts := runOptions{
+		repoPath:      *repoPath,
+		configPath:    *configPath,
+		outputFormats: outputFormats,
+		outputs:       outputs,
+		branch:        *branch,
+		explain:       *explain,
+		summary:       *summary,
+		deleted:       *deleted,
+		enforce:       *enforce,
+		failOn:        *failOn,
+		engine:        *engine,
+		regoPolicy:    *regoPolicy,
+	}
+	if err := run(opts); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
-func run(repoPath, configPath, outputFormat string) error {
-	// Detect Git branch
-	detector := git.NewDetector(repoPath)
-	branchInfo, err := detector.DetectBranch()
+// runOptions holds the flags for an evaluation run
+type runOptions struct {
+	repoPath      string
+	configPath    string
+	outputFormats []string
+	outputs       []string
+	branch        string
+	explain       bool
+	summary       bool
+	deleted       bool
+	enforce       bool
+	failOn        string
+	engine        string
+	regoPolicy    string
+}
+
+// stringList is a flag that may be given several times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func run(opts runOptions) error {
+	sinks, err := output.ParseSinks(opts.outputFormats, opts.outputs)
 	if err != nil {
-		return fmt.Errorf("failed to detect branch: %w", err)
+		return err
+	}
+	// Without a format the decision is printed for humans
+	if len(sinks) == 0 {
+		sinks = []output.Sink{{Format: output.FormatHuman}}
+	}
+	// The step summary and annotations can accompany any format
+	if opts.summary && !hasSink(sinks, output.FormatGitHubSummary) {
+		sinks = append(sinks, output.Sink{Format: output.FormatGitHubSummary})
 	}
 
-	// Load configuration
-	cfg, err := config.LoadConfig(configPath)
+	// The decision is made once and written in every requested format
+	decision, cfg, err := evaluate(opts)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	// Evaluate policy and make decision
-	engine := policy.NewEngine(cfg)
-	decision, err := engine.Evaluate(branchInfo)
+	threshold, err := failOnThreshold(opts.failOn, opts.enforce || cfg.Policies.Enforce)
 	if err != nil {
-		return fmt.Errorf("failed to evaluate policy: %w", err)
+		return err
 	}
 
-	// Format and output result
-	formatter := output.NewFormatter(output.Format(outputFormat))
-	result, err := formatter.Format(decision)
-	if err != nil {
-		return fmt.Errorf("failed to format output: %w", err)
+	if err := output.WriteSinks(decision, sinks, os.Stdout); err != nil {
+		return err
 	}
 
-	fmt.Println(result)
-	return nil
+	return enforceDecision(decision, threshold)
 }
 
-func initializeConfig(configPath string) error {
-	if configPath == "" {
-		configPath = ".branchci.yml"
+// hasSink reports whether one of sinks writes format
+func hasSink(sinks []output.Sink, format output.Format) bool {
+	for _, sink := range sinks {
+		if sink.Format == format {
+			return true
+		}
 	}
+	return false
+}
 
-	// Check if file already exists
-	if _, err := os.Stat(configPath); err == nil {
-		return fmt.Errorf("config file already exists: %s", configPath)
+// evaluate detects the branch, loads the configuration and makes a decision
+func evaluate(opts runOptions) (*policy.Decision, *config.Config, error) {
+	// A GitHub "delete" event names the deleted branch
+	if opts.branch == "" {
+		if name, ok := git.DeletedBranchFromEvent(); ok {
+			opts.branch, opts.deleted = name, true
+		}
 	}
 
-	// Create default config
-	cfg := config.DefaultConfig()
+	// Load configuration
+	cfg, err := config.LoadConfig(opts.configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
 
-	// Save to file
-	if err := config.SaveConfig(cfg, configPath); err != nil {
-		return err
+	// Promotion chains are checked against the recorded deployments
+	var engineOpts []policy.Option
+	if len(cfg.Promotion.Chains) > 0 {
+		h, err := history.Load(statePath(cfg))
+		if err != nil {
+			return nil, nil, err
+		}
+		engineOpts = append(engineOpts, policy.WithHistory(h))
 	}
 
-	return nil
+	extraOpts, err := engineOptions(cfg, opts.engine, opts.regoPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+	engineOpts = append(engineOpts, extraOpts...)
+
+	engine, err := policy.NewEngine(cfg, engineOpts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Detect Git branch; counting changed lines needs a full diff, so it is
+	// only done when the policy reads them
+	var detectorOpts []git.DetectorOption
+	if engine.NeedsLineStats() {
+		detectorOpts = append(detectorOpts, git.WithLineStats())
+	}
+	detector := git.NewDetector(opts.repoPath, detectorOpts...)
+	var branchInfo *git.BranchInfo
+	if opts.branch != "" {
+		branchInfo = detector.ReadBranch(opts.branch)
+	} else {
+		if branchInfo, err = detector.DetectBranch(); err != nil {
+			return nil, nil, fmt.Errorf("failed to detect branch: %w", err)
+		}
+	}
+	branchInfo.Deleted = opts.deleted
+
+	// Evaluate policy and make decision
+	var decision *policy.Decision
+	if opts.explain {
+		decision, err = engine.Explain(branchInfo)
+	} else {
+		decision, err = engine.Evaluate(branchInfo)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to evaluate policy: %w", err)
+	}
+
+	return decision, cfg, nil
+}
+
+// engineOptions returns the engine options for the plugins of the config and
+// for the policy engine selected by -engine and -rego
+func engineOptions(cfg *config.Config, engine, regoPath string) ([]policy.Option, error) {
+	var opts []policy.Option
+
+	// Plugins from the config file run on every decision
+	if len(cfg.Plugins) > 0 {
+		plugins, err := policy.NewPlugins(cfg.Plugins)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+		opts = append(opts, policy.WithPlugins(plugins))
+	}
+
+	// The rego engine layers a Rego policy on top of the built-in decision
+	switch engine {
+	case "", "builtin":
+	case "rego":
+		if regoPath == "" {
+			return nil, errors.New("-engine rego requires a -rego policy")
+		}
+		regoPolicy, err := policy.LoadRego(regoPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, policy.WithRego(regoPolicy))
+	default:
+		return nil, fmt.Errorf("invalid -engine value %q (expected builtin or rego)", engine)
+	}
+
+	return opts, nil
 }
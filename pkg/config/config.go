@@ -13,23 +13,80 @@ type Config struct {
 	Environments   map[string]EnvironmentConfig `yaml:"environments"`
 	BranchMappings []BranchMapping              `yaml:"branch_mappings"`
 	Policies       PolicyConfig                 `yaml:"policies"`
+	FreezeWindows  []FreezeWindow               `yaml:"freeze_windows,omitempty"`
+	Promotion      PromotionConfig              `yaml:"promotion,omitempty"`
+	Risk           *RiskConfig                  `yaml:"risk,omitempty"`
+	Actions        map[string]ActionConfig      `yaml:"actions,omitempty"`
+
+	// Plugins run local executables, so they are only taken from the CLI's
+	// config file; the policy engine service loads its own from PLUGINS_CONFIG
+	// and never runs plugins named in a request.
+	Plugins []PluginConfig `yaml:"plugins,omitempty"`
+}
+
+// PluginConfig configures an external policy plugin: an executable that
+// receives the branch and draft decision as JSON and returns a patch
+type PluginConfig struct {
+	Name      string   `yaml:"name"`
+	Command   string   `yaml:"command"`
+	Args      []string `yaml:"args,omitempty"`
+	Timeout   string   `yaml:"timeout,omitempty"`    // Go duration (default: 10s)
+	OnFailure string   `yaml:"on_failure,omitempty"` // closed (default) blocks deployment; open only warns
+}
+
+// ActionConfig declares a known action in the action catalog
+type ActionConfig struct {
+	Description string            `yaml:"description,omitempty"`
+	Needs       []string          `yaml:"needs,omitempty"`  // Actions that must run before this one
+	Params      map[string]string `yaml:"params,omitempty"` // Parameters passed along in the plan
 }
 
 // EnvironmentConfig defines settings for a specific environment
 type EnvironmentConfig struct {
-	Name             string            `yaml:"name"`
-	RequiresApproval bool              `yaml:"requires_approval"`
-	AllowedBranches  []string          `yaml:"allowed_branches"`
-	Variables        map[string]string `yaml:"variables"`
-	NotifyOnDeploy   bool              `yaml:"notify_on_deploy"`
+	Name             string               `yaml:"name"`
+	RequiresApproval bool                 `yaml:"requires_approval"`
+	AllowedBranches  []string             `yaml:"allowed_branches"`
+	Variables        map[string]string    `yaml:"variables"`
+	NotifyOnDeploy   bool                 `yaml:"notify_on_deploy"`
+	Approval         *ApprovalConfig      `yaml:"approval,omitempty"`
+	Template         *EnvironmentTemplate `yaml:"template,omitempty"`
+}
+
+// EnvironmentTemplate makes an environment a template for ephemeral
+// per-branch environments. Name, URL and the environment's variables are Go
+// templates rendered from the branch (.Branch, .Slug, .Type, .Metadata,
+// .CommitSHA and .Environment, the rendered name).
+type EnvironmentTemplate struct {
+	Name      string `yaml:"name"`                 // e.g. "preview-{{ .Slug }}"
+	MaxLength int    `yaml:"max_length,omitempty"` // Longest rendered name (default 63)
+	URL       string `yaml:"url,omitempty"`        // Rendered into PREVIEW_URL
+}
+
+// ApprovalConfig describes who must approve deployments to an environment.
+// Declaring it implies requires_approval.
+type ApprovalConfig struct {
+	Required          int      `yaml:"required,omitempty"` // Number of approvals (default 1)
+	Teams             []string `yaml:"teams,omitempty"`
+	Users             []string `yaml:"users,omitempty"`
+	AllowSelfApproval bool     `yaml:"allow_self_approval,omitempty"`
 }
 
-// BranchMapping maps branch patterns to environments
+// BranchMapping maps branch patterns to environments. A mapping targets
+// either one Environment or a list of Environments.
 type BranchMapping struct {
-	Pattern     string   `yaml:"pattern"`
-	Environment string   `yaml:"environment"`
-	Actions     []string `yaml:"actions"`
-	Priority    int      `yaml:"priority"`
+	Pattern      string   `yaml:"pattern"`
+	Environment  string   `yaml:"environment,omitempty"`
+	Environments []string `yaml:"environments,omitempty"`
+	Actions      []string `yaml:"actions"`
+	Priority     int      `yaml:"priority"`
+}
+
+// Targets returns the environments the mapping deploys to
+func (m BranchMapping) Targets() []string {
+	if len(m.Environments) > 0 {
+		return m.Environments
+	}
+	return []string{m.Environment}
 }
 
 // PolicyConfig defines CI/CD policies
@@ -38,6 +95,95 @@ type PolicyConfig struct {
 	RequireCodeReview     bool     `yaml:"require_code_review"`
 	BlockedBranchPatterns []string `yaml:"blocked_branch_patterns"`
 	AutoDeployBranches    []string `yaml:"auto_deploy_branches"`
+	Rules                 []Rule   `yaml:"rules,omitempty"`
+	Enforce               bool     `yaml:"enforce,omitempty"`
+}
+
+// Rule applies effects to a decision when its When expression holds.
+// Rules are evaluated in declaration order after the built-in policies.
+type Rule struct {
+	Name            string            `yaml:"name,omitempty"`
+	When            string            `yaml:"when"`
+	SetEnvironment  string            `yaml:"set_environment,omitempty"`
+	AddActions      []string          `yaml:"add_actions,omitempty"`
+	RemoveActions   []string          `yaml:"remove_actions,omitempty"`
+	RequireApproval bool              `yaml:"require_approval,omitempty"`
+	Block           bool              `yaml:"block,omitempty"`
+	SetVariables    map[string]string `yaml:"set_variables,omitempty"`
+	Warn            string            `yaml:"warn,omitempty"`
+	Severity        string            `yaml:"severity,omitempty"`
+}
+
+// FreezeWindow blocks deployments to some environments during a period of time.
+// A window either recurs (Cron marks each start, Duration its length) or covers
+// an absolute range from Start to End. Times are interpreted in Timezone (UTC
+// when empty).
+type FreezeWindow struct {
+	Name           string   `yaml:"name"`
+	Environments   []string `yaml:"environments,omitempty"`
+	Cron           string   `yaml:"cron,omitempty"`
+	Duration       string   `yaml:"duration,omitempty"`
+	Start          string   `yaml:"start,omitempty"`
+	End            string   `yaml:"end,omitempty"`
+	Timezone       string   `yaml:"timezone,omitempty"`
+	ExemptBranches []string `yaml:"exempt_branches,omitempty"`
+}
+
+// PromotionConfig defines environment promotion chains and where the record
+// of previous deployments is kept
+type PromotionConfig struct {
+	StateFile string           `yaml:"state_file,omitempty"`
+	Chains    []PromotionChain `yaml:"chains,omitempty"`
+}
+
+// PromotionChain orders the environments a commit moves through. A commit is
+// only eligible for an environment after it was deployed to the one before it.
+type PromotionChain struct {
+	Name         string   `yaml:"name,omitempty"`
+	Environments []string `yaml:"environments"`
+	OnIneligible string   `yaml:"on_ineligible,omitempty"` // "warn" (default) or "block"
+}
+
+// RiskConfig defines how a decision's risk score is computed. The score is
+// the sum of the weights of every factor that applies.
+type RiskConfig struct {
+	BranchTypes    map[string]int  `yaml:"branch_types,omitempty"` // Weight per branch type
+	Environments   map[string]int  `yaml:"environments,omitempty"` // Weight per target environment
+	Changes        RiskChanges     `yaml:"changes,omitempty"`
+	SensitivePaths []RiskPath      `yaml:"sensitive_paths,omitempty"`
+	OffHours       *RiskOffHours   `yaml:"off_hours,omitempty"`
+	Thresholds     []RiskThreshold `yaml:"thresholds,omitempty"`
+}
+
+// RiskChanges weighs the size of the change
+type RiskChanges struct {
+	PerFile         int `yaml:"per_file,omitempty"`          // Points per changed file
+	PerHundredLines int `yaml:"per_hundred_lines,omitempty"` // Points per 100 changed lines
+	Max             int `yaml:"max,omitempty"`               // Cap on the change size points (0 for none)
+}
+
+// RiskPath adds Weight when a changed file matches Pattern
+type RiskPath struct {
+	Pattern string `yaml:"pattern"`
+	Weight  int    `yaml:"weight"`
+}
+
+// RiskOffHours adds Weight outside working hours: from Start until End
+// (hours 0-23, wrapping past midnight) and, with Weekends, on Saturday and Sunday
+type RiskOffHours struct {
+	Start    int    `yaml:"start"`
+	End      int    `yaml:"end"`
+	Weekends bool   `yaml:"weekends,omitempty"`
+	Timezone string `yaml:"timezone,omitempty"`
+	Weight   int    `yaml:"weight"`
+}
+
+// RiskThreshold applies effects to decisions whose score reaches Score
+type RiskThreshold struct {
+	Name            string   `yaml:"name"`
+	Score           int      `yaml:"score"`
+	RequireApproval bool     `yaml:"require_approval,omitempty"`
+	AddActions      []string `yaml:"add_actions,omitempty"`
 }
 
 // DefaultConfig returns a sensible default configuration
@@ -95,7 +241,7 @@ func DefaultConfig() *Config {
 func LoadConfig(configPath string) (*Config, error) {
 	// If config path is empty, try default locations
 	if configPath == "" {
-		configPath = findConfigFile()
+		configPath = FindConfigFile()
 	}
 
 	// If no config file found, use defaults
@@ -114,6 +260,11 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	return ParseConfig(data)
+}
+
+// ParseConfig parses the contents of a config file
+func ParseConfig(data []byte) (*Config, error) {
 	var config Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
@@ -122,16 +273,18 @@ func LoadConfig(configPath string) (*Config, error) {
 	return &config, nil
 }
 
-// findConfigFile searches for config file in common locations
-func findConfigFile() string {
-	possiblePaths := []string{
-		".branchci.yml",
-		".branchci.yaml",
-		".github/branchci.yml",
-		".github/branchci.yaml",
-	}
+// ConfigPaths are the default config file locations, in lookup order
+var ConfigPaths = []string{
+	".branchci.yml",
+	".branchci.yaml",
+	".github/branchci.yml",
+	".github/branchci.yaml",
+}
 
-	for _, path := range possiblePaths {
+// FindConfigFile searches for a config file in the default locations and
+// returns its path, or "" when there is none
+func FindConfigFile() string {
+	for _, path := range ConfigPaths {
 		if _, err := os.Stat(path); err == nil {
 			return path
 		}
@@ -0,0 +1,145 @@
+package config
+
+import "github.com/NadeeshaMedagama/branch_aware_ci/pkg/interfaces"
+
+// FromInterfaces converts the service configuration type into a Config
+func FromInterfaces(c *interfaces.Config) *Config {
+	if c == nil {
+		return nil
+	}
+
+	cfg := &Config{
+		Environments: make(map[string]EnvironmentConfig, len(c.Environments)),
+		Policies: PolicyConfig{
+			RequireTests:          c.Policies.RequireTests,
+			RequireCodeReview:     c.Policies.RequireCodeReview,
+			BlockedBranchPatterns: c.Policies.BlockedBranchPatterns,
+			AutoDeployBranches:    c.Policies.AutoDeployBranches,
+			Enforce:               c.Policies.Enforce,
+		},
+	}
+
+	for name, env := range c.Environments {
+		cfg.Environments[name] = EnvironmentConfig{
+			Name:             env.Name,
+			RequiresApproval: env.RequiresApproval,
+			AllowedBranches:  env.AllowedBranches,
+			Variables:        env.Variables,
+			NotifyOnDeploy:   env.NotifyOnDeploy,
+			Approval:         (*ApprovalConfig)(env.Approval),
+			Template:         (*EnvironmentTemplate)(env.Template),
+		}
+	}
+
+	for _, rule := range c.Policies.Rules {
+		cfg.Policies.Rules = append(cfg.Policies.Rules, Rule(rule))
+	}
+
+	for _, window := range c.FreezeWindows {
+		cfg.FreezeWindows = append(cfg.FreezeWindows, FreezeWindow(window))
+	}
+
+	cfg.Promotion.StateFile = c.Promotion.StateFile
+	for _, chain := range c.Promotion.Chains {
+		cfg.Promotion.Chains = append(cfg.Promotion.Chains, PromotionChain(chain))
+	}
+
+	if c.Risk != nil {
+		cfg.Risk = &RiskConfig{
+			BranchTypes:  c.Risk.BranchTypes,
+			Environments: c.Risk.Environments,
+			Changes:      RiskChanges(c.Risk.Changes),
+			OffHours:     (*RiskOffHours)(c.Risk.OffHours),
+		}
+		for _, path := range c.Risk.SensitivePaths {
+			cfg.Risk.SensitivePaths = append(cfg.Risk.SensitivePaths, RiskPath(path))
+		}
+		for _, threshold := range c.Risk.Thresholds {
+			cfg.Risk.Thresholds = append(cfg.Risk.Thresholds, RiskThreshold(threshold))
+		}
+	}
+
+	for _, mapping := range c.BranchMappings {
+		cfg.BranchMappings = append(cfg.BranchMappings, BranchMapping(mapping))
+	}
+
+	if len(c.Actions) > 0 {
+		cfg.Actions = make(map[string]ActionConfig, len(c.Actions))
+		for name, action := range c.Actions {
+			cfg.Actions[name] = ActionConfig(action)
+		}
+	}
+
+	return cfg
+}
+
+// ToInterfaces converts the Config into the service configuration type
+func (c *Config) ToInterfaces() *interfaces.Config {
+	if c == nil {
+		return nil
+	}
+
+	cfg := &interfaces.Config{
+		Environments: make(map[string]interfaces.EnvironmentConfig, len(c.Environments)),
+		Policies: interfaces.PolicyConfig{
+			RequireTests:          c.Policies.RequireTests,
+			RequireCodeReview:     c.Policies.RequireCodeReview,
+			BlockedBranchPatterns: c.Policies.BlockedBranchPatterns,
+			AutoDeployBranches:    c.Policies.AutoDeployBranches,
+			Enforce:               c.Policies.Enforce,
+		},
+	}
+
+	for name, env := range c.Environments {
+		cfg.Environments[name] = interfaces.EnvironmentConfig{
+			Name:             env.Name,
+			RequiresApproval: env.RequiresApproval,
+			AllowedBranches:  env.AllowedBranches,
+			Variables:        env.Variables,
+			NotifyOnDeploy:   env.NotifyOnDeploy,
+			Approval:         (*interfaces.Approval)(env.Approval),
+			Template:         (*interfaces.EnvironmentTemplate)(env.Template),
+		}
+	}
+
+	for _, rule := range c.Policies.Rules {
+		cfg.Policies.Rules = append(cfg.Policies.Rules, interfaces.Rule(rule))
+	}
+
+	for _, window := range c.FreezeWindows {
+		cfg.FreezeWindows = append(cfg.FreezeWindows, interfaces.FreezeWindow(window))
+	}
+
+	cfg.Promotion.StateFile = c.Promotion.StateFile
+	for _, chain := range c.Promotion.Chains {
+		cfg.Promotion.Chains = append(cfg.Promotion.Chains, interfaces.PromotionChain(chain))
+	}
+
+	if c.Risk != nil {
+		cfg.Risk = &interfaces.RiskConfig{
+			BranchTypes:  c.Risk.BranchTypes,
+			Environments: c.Risk.Environments,
+			Changes:      interfaces.RiskChanges(c.Risk.Changes),
+			OffHours:     (*interfaces.RiskOffHours)(c.Risk.OffHours),
+		}
+		for _, path := range c.Risk.SensitivePaths {
+			cfg.Risk.SensitivePaths = append(cfg.Risk.SensitivePaths, interfaces.RiskPath(path))
+		}
+		for _, threshold := range c.Risk.Thresholds {
+			cfg.Risk.Thresholds = append(cfg.Risk.Thresholds, interfaces.RiskThreshold(threshold))
+		}
+	}
+
+	for _, mapping := range c.BranchMappings {
+		cfg.BranchMappings = append(cfg.BranchMappings, interfaces.BranchMapping(mapping))
+	}
+
+	if len(c.Actions) > 0 {
+		cfg.Actions = make(map[string]interfaces.ActionConfig, len(c.Actions))
+		for name, action := range c.Actions {
+			cfg.Actions[name] = interfaces.ActionConfig(action)
+		}
+	}
+
+	return cfg
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Preset identifies a branching model used to generate an initial configuration
+type Preset string
+
+const (
+	PresetGitFlow    Preset = "gitflow"
+	PresetTrunk      Preset = "trunk"
+	PresetGitHubFlow Preset = "github-flow"
+)
+
+// Presets lists all supported branching models
+var Presets = []Preset{PresetGitFlow, PresetTrunk, PresetGitHubFlow}
+
+// environmentTiers lists the known environments from most to least critical
+var environmentTiers = []string{"production", "staging", "development"}
+
+// PresetOptions tailors a preset configuration to a repository
+type PresetOptions struct {
+	MainBranch   string   // Name of the primary branch ("main" or "master")
+	Environments []string // Environments to generate; empty means all known tiers
+}
+
+// ParsePreset validates a preset name
+func ParsePreset(name string) (Preset, error) {
+	for _, p := range Presets {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown preset %q (expected one of: gitflow, trunk, github-flow)", name)
+}
+
+// DetectMainBranch returns the primary branch name found in branches,
+// preferring "main" over "master" and falling back to "main"
+func DetectMainBranch(branches []string) string {
+	if containsString(branches, "main") {
+		return "main"
+	}
+	if containsString(branches, "master") {
+		return "master"
+	}
+	return "main"
+}
+
+// SuggestPreset proposes a branching model based on the existing branches.
+// A develop branch indicates gitflow, release branches without develop
+// indicate trunk-based development, and anything else is treated as GitHub flow.
+func SuggestPreset(branches []string) Preset {
+	if containsString(branches, "develop") || containsString(branches, "development") {
+		return PresetGitFlow
+	}
+	for _, b := range branches {
+		if strings.HasPrefix(b, "release/") {
+			return PresetTrunk
+		}
+	}
+	return PresetGitHubFlow
+}
+
+// NewPresetConfig generates a configuration for the given branching model
+func NewPresetConfig(preset Preset, opts PresetOptions) (*Config, error) {
+	mainBranch := opts.MainBranch
+	if mainBranch == "" {
+		mainBranch = "main"
+	}
+
+	var mappings []BranchMapping
+	var stagingBranches []string
+	switch preset {
+	case PresetGitFlow:
+		stagingBranches = []string{"develop", "release/*", "hotfix/*"}
+		mappings = []BranchMapping{
+			{Pattern: mainBranch, Environment: "production", Actions: []string{"test", "deploy", "notify"}, Priority: 100},
+			{Pattern: "release/*", Environment: "staging", Actions: []string{"test", "deploy"}, Priority: 85},
+			{Pattern: "develop", Environment: "staging", Actions: []string{"test", "deploy"}, Priority: 80},
+			{Pattern: "hotfix/*", Environment: "staging", Actions: []string{"test", "deploy"}, Priority: 70},
+			{Pattern: "feature/*", Environment: "development", Actions: []string{"test"}, Priority: 50},
+			{Pattern: "bugfix/*", Environment: "development", Actions: []string{"test"}, Priority: 50},
+		}
+	case PresetTrunk:
+		stagingBranches = []string{"release/*"}
+		mappings = []BranchMapping{
+			{Pattern: mainBranch, Environment: "production", Actions: []string{"test", "deploy", "notify"}, Priority: 100},
+			{Pattern: "release/*", Environment: "staging", Actions: []string{"test", "deploy"}, Priority: 85},
+			{Pattern: "feature/*", Environment: "development", Actions: []string{"test"}, Priority: 50},
+			{Pattern: "bugfix/*", Environment: "development", Actions: []string{"test"}, Priority: 50},
+		}
+	case PresetGitHubFlow:
+		stagingBranches = []string{"hotfix/*"}
+		mappings = []BranchMapping{
+			{Pattern: mainBranch, Environment: "production", Actions: []string{"test", "deploy", "notify"}, Priority: 100},
+			{Pattern: "hotfix/*", Environment: "staging", Actions: []string{"test", "deploy"}, Priority: 70},
+			{Pattern: "feature/*", Environment: "development", Actions: []string{"test"}, Priority: 50},
+			{Pattern: "bugfix/*", Environment: "development", Actions: []string{"test"}, Priority: 50},
+		}
+	default:
+		return nil, fmt.Errorf("unknown preset %q", preset)
+	}
+
+	environments := opts.Environments
+	if len(environments) == 0 {
+		environments = environmentTiers
+	}
+
+	allEnvironments := map[string]EnvironmentConfig{
+		"production": {
+			Name:             "production",
+			RequiresApproval: true,
+			AllowedBranches:  []string{mainBranch},
+			Variables:        map[string]string{"ENV": "production"},
+			NotifyOnDeploy:   true,
+		},
+		"staging": {
+			Name:             "staging",
+			RequiresApproval: false,
+			AllowedBranches:  stagingBranches,
+			Variables:        map[string]string{"ENV": "staging"},
+			NotifyOnDeploy:   true,
+		},
+		"development": {
+			Name:             "development",
+			RequiresApproval: false,
+			AllowedBranches:  []string{"feature/*", "bugfix/*"},
+			Variables:        map[string]string{"ENV": "development"},
+			NotifyOnDeploy:   false,
+		},
+	}
+
+	cfg := &Config{
+		Environments: make(map[string]EnvironmentConfig),
+		Policies: PolicyConfig{
+			RequireTests:          true,
+			RequireCodeReview:     true,
+			BlockedBranchPatterns: []string{},
+			AutoDeployBranches:    []string{mainBranch},
+		},
+	}
+	// Environments other than the known tiers get no mappings
+	for _, env := range environments {
+		envConfig, known := allEnvironments[env]
+		if !known {
+			envConfig = EnvironmentConfig{
+				Name:      env,
+				Variables: map[string]string{"ENV": env},
+			}
+		}
+		cfg.Environments[env] = envConfig
+	}
+
+	// Point mappings for omitted environments at the next less critical one
+	for _, mapping := range mappings {
+		env := fallbackEnvironment(mapping.Environment, environments)
+		if env == "" {
+			continue
+		}
+		if env != mapping.Environment {
+			envConfig := cfg.Environments[env]
+			envConfig.AllowedBranches = append(envConfig.AllowedBranches, mapping.Pattern)
+			cfg.Environments[env] = envConfig
+		}
+		mapping.Environment = env
+		cfg.BranchMappings = append(cfg.BranchMappings, mapping)
+	}
+
+	return cfg, nil
+}
+
+// fallbackEnvironment returns env if selected, otherwise the next less
+// critical selected environment, or "" if there is none
+func fallbackEnvironment(env string, selected []string) string {
+	found := false
+	for _, tier := range environmentTiers {
+		if tier == env {
+			found = true
+		}
+		if found && containsString(selected, tier) {
+			return tier
+		}
+	}
+	return ""
+}
+
+// containsString checks if a slice contains a string
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package config
+
+import "testing"
+
+func TestSuggestPreset(t *testing.T) {
+	tests := []struct {
+		name     string
+		branches []string
+		expected Preset
+	}{
+		{"develop branch", []string{"main", "develop", "feature/a"}, PresetGitFlow},
+		{"release branches", []string{"main", "release/1.0"}, PresetTrunk},
+		{"main only", []string{"main", "feature/a"}, PresetGitHubFlow},
+		{"empty repository", nil, PresetGitHubFlow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuggestPreset(tt.branches); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDetectMainBranch(t *testing.T) {
+	if got := DetectMainBranch([]string{"develop", "master"}); got != "master" {
+		t.Errorf("Expected master, got %s", got)
+	}
+	if got := DetectMainBranch([]string{"main", "master"}); got != "main" {
+		t.Errorf("Expected main, got %s", got)
+	}
+	if got := DetectMainBranch(nil); got != "main" {
+		t.Errorf("Expected main, got %s", got)
+	}
+}
+
+func TestNewPresetConfig(t *testing.T) {
+	cfg, err := NewPresetConfig(PresetGitFlow, PresetOptions{MainBranch: "master"})
+	if err != nil {
+		t.Fatalf("NewPresetConfig failed: %v", err)
+	}
+	if len(cfg.Environments) != 3 {
+		t.Errorf("Expected 3 environments, got %d", len(cfg.Environments))
+	}
+	if cfg.BranchMappings[0].Pattern != "master" {
+		t.Errorf("Expected primary mapping for master, got %s", cfg.BranchMappings[0].Pattern)
+	}
+
+	// Omitting staging moves its branches to development
+	cfg, err = NewPresetConfig(PresetGitFlow, PresetOptions{Environments: []string{"production", "development"}})
+	if err != nil {
+		t.Fatalf("NewPresetConfig failed: %v", err)
+	}
+	if _, exists := cfg.Environments["staging"]; exists {
+		t.Error("Expected staging environment to be omitted")
+	}
+	for _, mapping := range cfg.BranchMappings {
+		if mapping.Pattern == "develop" && mapping.Environment != "development" {
+			t.Errorf("Expected develop to fall back to development, got %s", mapping.Environment)
+		}
+	}
+
+	// Other environments are added without mappings
+	cfg, err = NewPresetConfig(PresetTrunk, PresetOptions{Environments: []string{"production", "perf", "production-eu"}})
+	if err != nil {
+		t.Fatalf("NewPresetConfig failed: %v", err)
+	}
+	for _, name := range []string{"perf", "production-eu"} {
+		env, exists := cfg.Environments[name]
+		if !exists || env.Name != name || env.Variables["ENV"] != name || env.RequiresApproval {
+			t.Errorf("Expected a plain %s environment, got %+v", name, env)
+		}
+	}
+	for _, mapping := range cfg.BranchMappings {
+		if mapping.Environment != "production" {
+			t.Errorf("Expected only production mappings, got %s → %s", mapping.Pattern, mapping.Environment)
+		}
+	}
+	if _, err := ParsePreset("waterfall"); err == nil {
+		t.Error("Expected error for unknown preset")
+	}
+}
@@ -0,0 +1,210 @@
+// Package diff compares the decisions two configurations make for the same
+// branches, e.g. the configuration on a pull request's base and head.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+// Change is a decision field whose value differs between the two
+// configurations. An empty value means the field is not set.
+type Change struct {
+	Field  string `json:"field"` // e.g. "environment", "variables.ENV"
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// BranchDiff lists the changes to the decision for one branch
+type BranchDiff struct {
+	Branch  string   `json:"branch"`
+	Changes []Change `json:"changes"`
+}
+
+// Report is the result of comparing the decisions of two configurations
+type Report struct {
+	Base     string       `json:"base"`
+	Head     string       `json:"head"`
+	Branches []BranchDiff `json:"branches"`
+}
+
+// Changed returns the branches whose decision changed
+func (r *Report) Changed() []BranchDiff {
+	var changed []BranchDiff
+	for _, b := range r.Branches {
+		if len(b.Changes) > 0 {
+			changed = append(changed, b)
+		}
+	}
+	return changed
+}
+
+// Unchanged returns the branches whose decision did not change
+func (r *Report) Unchanged() []string {
+	var unchanged []string
+	for _, b := range r.Branches {
+		if len(b.Changes) == 0 {
+			unchanged = append(unchanged, b.Branch)
+		}
+	}
+	return unchanged
+}
+
+// ignoredFields are decision fields left out of the comparison: they
+// describe the branch rather than the decision, or repeat other fields
+var ignoredFields = map[string]bool{
+	"branch_name": true,
+	"branch_type": true,
+	"commit_sha":  true,
+	"metadata":    true,
+	"trace":       true,
+	"findings":    true, // Compared through warnings
+	"plan":        true, // Compared through actions
+	"factors":     true, // Compared through the risk score
+}
+
+// Compare evaluates every branch under both configurations at the same time
+// and reports the fields that differ
+func Compare(base, head *config.Config, branches []string, now time.Time) ([]BranchDiff, error) {
+	clock := policy.WithClock(func() time.Time { return now })
+	baseEngine, err := policy.NewEngine(base, clock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base config: %w", err)
+	}
+	headEngine, err := policy.NewEngine(head, clock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid head config: %w", err)
+	}
+
+	detector := git.NewDetector("")
+	diffs := make([]BranchDiff, 0, len(branches))
+	for _, branch := range branches {
+		before, err := baseEngine.Evaluate(detector.GetBranchInfo(branch))
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %s under the base config: %w", branch, err)
+		}
+		after, err := headEngine.Evaluate(detector.GetBranchInfo(branch))
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %s under the head config: %w", branch, err)
+		}
+
+		changes, err := compareDecisions(before, after)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, BranchDiff{Branch: branch, Changes: changes})
+	}
+	return diffs, nil
+}
+
+// compareDecisions returns the fields that differ between two decisions, in
+// field order
+func compareDecisions(before, after *policy.Decision) ([]Change, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(beforeFields)+len(afterFields))
+	for name := range beforeFields {
+		names[name] = true
+	}
+	for name := range afterFields {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	changes := []Change{}
+	for _, name := range sorted {
+		if beforeFields[name] != afterFields[name] {
+			changes = append(changes, Change{Field: name, Before: beforeFields[name], After: afterFields[name]})
+		}
+	}
+	return changes, nil
+}
+
+// fields flattens a decision into field paths and their values, such as
+// "variables.ENV" or "environments.staging.should_deploy"
+func fields(decision *policy.Decision) (map[string]string, error) {
+	data, err := json.Marshal(decision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal decision: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal decision: %w", err)
+	}
+
+	// Environments are compared by name rather than by position
+	if envs, ok := doc["environments"].([]interface{}); ok {
+		byName := make(map[string]interface{}, len(envs))
+		for _, env := range envs {
+			if env, ok := env.(map[string]interface{}); ok {
+				name, _ := env["environment"].(string)
+				delete(env, "environment")
+				byName[name] = env
+			}
+		}
+		doc["environments"] = byName
+	}
+
+	result := make(map[string]string)
+	flatten("", doc, result)
+	return result, nil
+}
+
+// flatten adds the leaves of value to result under their dotted paths
+func flatten(path string, value interface{}, result map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if ignoredFields[k] {
+				continue
+			}
+			if path != "" {
+				k = path + "." + k
+			}
+			flatten(k, child, result)
+		}
+	case nil:
+	default:
+		result[path] = show(v)
+	}
+}
+
+// show renders a field value, listing string arrays as "[a, b]"
+func show(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				data, _ := json.Marshal(v)
+				return string(data)
+			}
+			items = append(items, s)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
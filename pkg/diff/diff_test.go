@@ -0,0 +1,91 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+)
+
+func TestCompare(t *testing.T) {
+	base := config.DefaultConfig()
+	head := config.DefaultConfig()
+	head.BranchMappings = append([]config.BranchMapping{}, head.BranchMappings...)
+	for i, mapping := range head.BranchMappings {
+		if mapping.Pattern == "release/*" {
+			head.BranchMappings[i].Environments = []string{"staging", "production"}
+			head.BranchMappings[i].Environment = ""
+		}
+	}
+
+	diffs, err := Compare(base, head, []string{"main", "release/1.0"}, time.Now())
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if len(diffs) != 2 || diffs[0].Branch != "main" || len(diffs[0].Changes) != 0 {
+		t.Fatalf("Expected main to be unchanged, got %+v", diffs)
+	}
+
+	changes := make(map[string]Change)
+	for _, c := range diffs[1].Changes {
+		changes[c.Field] = c
+	}
+	if c := changes["environments.production.should_deploy"]; c.Before != "" || c.After != "true" {
+		t.Errorf("Expected release/1.0 to also deploy to production, got %+v", diffs[1].Changes)
+	}
+	if c := changes["environments.staging.variables.ENV"]; c.Before != "" || c.After != "staging" {
+		t.Errorf("Expected the staging variables to be listed, got %+v", diffs[1].Changes)
+	}
+	if _, exists := changes["environment"]; exists {
+		t.Errorf("Expected the primary environment to be unchanged, got %+v", changes["environment"])
+	}
+
+	head.BranchMappings[0].Pattern = "re:("
+	if _, err := Compare(base, head, []string{"main"}, time.Now()); err == nil || !strings.Contains(err.Error(), "invalid head config") {
+		t.Errorf("Expected an invalid head config error, got %v", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	report := &Report{
+		Base: "origin/main",
+		Head: "HEAD",
+		Branches: []BranchDiff{
+			{Branch: "main", Changes: []Change{}},
+			{Branch: "release/1.0", Changes: []Change{
+				{Field: "environment", Before: "staging", After: "production"},
+				{Field: "variables.REGION", After: "eu|us"},
+			}},
+		},
+	}
+
+	human, err := Format(report, "human")
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	for _, want := range []string{"release/1.0", "  - environment: staging → production", "  - variables.REGION: (unset) → eu|us", "Unchanged: main"} {
+		if !strings.Contains(human, want) {
+			t.Errorf("Expected human output to contain %q, got:\n%s", want, human)
+		}
+	}
+
+	markdown, err := Format(report, "markdown")
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	for _, want := range []string{"(`origin/main` → `HEAD`)", "| `environment` | `staging` | `production` |", "| `variables.REGION` | _unset_ | `eu\\|us` |", "Unchanged: `main`"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected markdown output to contain %q, got:\n%s", want, markdown)
+		}
+	}
+
+	json, err := Format(report, "json")
+	if err != nil || !strings.Contains(json, `"field": "variables.REGION",`+"\n"+`          "after": "eu|us"`) {
+		t.Errorf("Unexpected JSON output (%v):\n%s", err, json)
+	}
+
+	if _, err := Format(report, "xml"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Format formats the report as human, json or markdown output
+func Format(report *Report, format string) (string, error) {
+	switch format {
+	case "human":
+		return formatHuman(report), nil
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		return string(data), nil
+	case "markdown":
+		return formatMarkdown(report), nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// formatHuman lists the changes of every branch for a terminal
+func formatHuman(report *Report) string {
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf("🔀 Decision Changes (%s → %s)", report.Base, report.Head))
+	lines = append(lines, "==================")
+
+	changed := report.Changed()
+	if len(changed) == 0 {
+		lines = append(lines, fmt.Sprintf("No changes for %d branches", len(report.Branches)))
+		return strings.Join(lines, "\n")
+	}
+
+	for _, b := range changed {
+		lines = append(lines, b.Branch)
+		for _, c := range b.Changes {
+			lines = append(lines, fmt.Sprintf("  - %s: %s → %s", c.Field, orUnset(c.Before), orUnset(c.After)))
+		}
+	}
+	if unchanged := report.Unchanged(); len(unchanged) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("Unchanged: %s", strings.Join(unchanged, ", ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// formatMarkdown renders the changes as a pull request comment
+func formatMarkdown(report *Report) string {
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf("### 🔀 Deployment decision changes (`%s` → `%s`)", report.Base, report.Head))
+	lines = append(lines, "")
+
+	changed := report.Changed()
+	if len(changed) == 0 {
+		lines = append(lines, fmt.Sprintf("No changes for the %d branches evaluated.", len(report.Branches)))
+		return strings.Join(lines, "\n")
+	}
+
+	for _, b := range changed {
+		lines = append(lines, fmt.Sprintf("**`%s`**", b.Branch))
+		lines = append(lines, "")
+		lines = append(lines, "| Field | Before | After |")
+		lines = append(lines, "|-------|--------|-------|")
+		for _, c := range b.Changes {
+			lines = append(lines, fmt.Sprintf("| `%s` | %s | %s |", c.Field, markdownValue(c.Before), markdownValue(c.After)))
+		}
+		lines = append(lines, "")
+	}
+	if unchanged := report.Unchanged(); len(unchanged) > 0 {
+		names := make([]string, len(unchanged))
+		for i, name := range unchanged {
+			names[i] = "`" + name + "`"
+		}
+		lines = append(lines, fmt.Sprintf("Unchanged: %s", strings.Join(names, ", ")))
+	}
+
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// orUnset returns the value, or "(unset)" when it is empty
+func orUnset(value string) string {
+	if value == "" {
+		return "(unset)"
+	}
+	return value
+}
+
+// markdownValue renders a value as code in a table cell
+func markdownValue(value string) string {
+	if value == "" {
+		return "_unset_"
+	}
+	value = strings.ReplaceAll(value, "|", `\|`)
+	value = strings.ReplaceAll(value, "\n", " ")
+	return "`" + value + "`"
+}
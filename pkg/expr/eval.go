@@ -0,0 +1,314 @@
+package expr
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// node is an evaluable expression tree node
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(Env) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	path []string
+}
+
+func (n *identNode) eval(env Env) (interface{}, error) {
+	var current interface{} = map[string]interface{}(env)
+	for _, key := range n.path {
+		switch m := current.(type) {
+		case map[string]interface{}:
+			current = m[key]
+		case Env:
+			current = m[key]
+		case map[string]string:
+			v, ok := m[key]
+			if !ok {
+				return nil, nil
+			}
+			current = v
+		default:
+			return nil, nil
+		}
+	}
+	return normalize(current), nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(env Env) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicalNode) eval(env Env) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(left) != n.and {
+		// Short-circuit: false && x, true || x
+		return !n.and, nil
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(right), nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(env Env) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	}
+
+	// Ordering against a missing value is false rather than an error so that
+	// rules referring to optional data simply do not fire
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %s", typeName(right))
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %s", typeName(right))
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, fmt.Errorf("cannot order %s values", typeName(left))
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+type matchNode struct {
+	left, right node
+	re          *regexp.Regexp // set when the pattern is a literal
+}
+
+func (n *matchNode) eval(env Env) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := left.(string)
+	if !ok {
+		return false, nil
+	}
+
+	re := n.re
+	if re == nil {
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		pattern, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("matches requires a string pattern, got %s", typeName(right))
+		}
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+	}
+	return re.MatchString(s), nil
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n *callNode) eval(env Env) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+// splitPath splits a dotted identifier into its segments
+func splitPath(ident string) []string {
+	return strings.Split(ident, ".")
+}
+
+// normalize converts environment values to the expression value types
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil, bool, string, float64, []interface{}:
+		return val
+	case int:
+		return float64(val)
+	case int32:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case uint:
+		return float64(val)
+	case float32:
+		return float64(val)
+	case []string:
+		list := make([]interface{}, len(val))
+		for i, s := range val {
+			list[i] = s
+		}
+		return list
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice {
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = normalize(rv.Index(i).Interface())
+		}
+		return list
+	}
+	return v
+}
+
+// truthy reports whether a value counts as true in a condition
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	case float64:
+		return val != 0
+	case []interface{}:
+		return len(val) > 0
+	}
+	return true
+}
+
+// equal compares two values
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// contains reports whether item is an element of a list or a substring of a string
+func contains(container, item interface{}) (bool, error) {
+	switch c := container.(type) {
+	case nil:
+		return false, nil
+	case []interface{}:
+		for _, v := range c {
+			if equal(v, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot search string for %s", typeName(item))
+		}
+		return strings.Contains(c, s), nil
+	}
+	return false, fmt.Errorf("cannot search %s", typeName(container))
+}
+
+// typeName names a value type in error messages
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "list"
+	}
+	return fmt.Sprintf("%T", v)
+}
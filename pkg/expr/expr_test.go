@@ -0,0 +1,103 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvalBool(t *testing.T) {
+	env := Env{
+		"branch": map[string]interface{}{
+			"name":      "hotfix/OPS-12-login",
+			"type":      "hotfix",
+			"protected": false,
+		},
+		"metadata": map[string]string{"ticket": "OPS-12"},
+		"commit": map[string]interface{}{
+			"files":         []string{"docs/readme.md", "migrations/001.sql"},
+			"files_changed": 2,
+		},
+		"target": "main",
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`branch.type == "hotfix"`, true},
+		{`branch.type != 'hotfix'`, false},
+		{`branch.type == "hotfix" && target == "main" && commit.files_changed > 1`, true},
+		{`branch.type == "hotfix" and commit.files_changed >= 3`, false},
+		{`branch.protected || target == "main"`, true},
+		{`!branch.protected`, true},
+		{`not (branch.protected or target == "develop")`, true},
+		{`metadata.ticket in ["OPS-12", "OPS-13"]`, true},
+		{`"OPS" in metadata.ticket`, true},
+		{`metadata.missing == null`, true},
+		{`metadata.missing > 3`, false},
+		{`metadata.missing`, false},
+		{`branch.name matches "^hotfix/OPS-[0-9]+"`, true},
+		{`starts_with(branch.name, "hotfix/") && ends_with(branch.name, "login")`, true},
+		{`glob(commit.files, "migrations/**")`, true},
+		{`glob(commit.files, "src/**")`, false},
+		{`len(commit.files) == 2`, true},
+		{`lower(metadata.ticket) == "ops-12"`, true},
+		{`contains(commit.files, "docs/readme.md")`, true},
+		{`unknown.path.here`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			result, err := e.EvalBool(env)
+			if err != nil {
+				t.Fatalf("Eval failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`branch.type ==`, "unexpected end"},
+		{`branch.type == "hotfix`, "unterminated string"},
+		{`(a == b`, `expected ")"`},
+		{`a == b c`, `unexpected "c"`},
+		{`a # b`, "unexpected character"},
+		{`nope(a)`, `unknown function "nope"`},
+		{`len(a, b)`, "expects 1 arguments"},
+		{`a matches "["`, "invalid regular expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	e, err := Compile(`branch.name > 3`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	_, err = e.EvalBool(Env{"branch": map[string]interface{}{"name": "main"}})
+	if err == nil || !strings.Contains(err.Error(), "cannot compare string with number") {
+		t.Errorf("Expected a type error, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/pattern"
+)
+
+// function is a built-in function; an arity of -1 accepts any number of arguments
+type function struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+// functions lists the built-in functions available to expressions
+var functions = map[string]function{
+	"len":         {1, fnLen},
+	"lower":       {1, stringFunc(strings.ToLower)},
+	"upper":       {1, stringFunc(strings.ToUpper)},
+	"contains":    {2, fnContains},
+	"starts_with": {2, stringPredicate(strings.HasPrefix)},
+	"ends_with":   {2, stringPredicate(strings.HasSuffix)},
+	"glob":        {2, fnGlob},
+}
+
+// fnLen returns the length of a string or list
+func fnLen(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(len(v)), nil
+	case []interface{}:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("cannot take length of %s", typeName(args[0]))
+}
+
+// fnContains reports whether a list or string contains a value
+func fnContains(args []interface{}) (interface{}, error) {
+	return contains(args[0], args[1])
+}
+
+// fnGlob reports whether a value, or any element of a list, matches a branch pattern
+func fnGlob(args []interface{}) (interface{}, error) {
+	raw, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("pattern must be a string, got %s", typeName(args[1]))
+	}
+	p, err := pattern.Compile(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := args[0].(type) {
+	case nil:
+		return false, nil
+	case string:
+		return p.Match(v), nil
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && p.Match(s) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return nil, fmt.Errorf("cannot match %s", typeName(args[0]))
+}
+
+// stringFunc adapts a string transformation into a function
+func stringFunc(f func(string) string) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case nil:
+			return "", nil
+		case string:
+			return f(v), nil
+		}
+		return nil, fmt.Errorf("expected a string, got %s", typeName(args[0]))
+	}
+}
+
+// stringPredicate adapts a two-string predicate into a function
+func stringPredicate(f func(string, string) bool) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		s, _ := args[0].(string)
+		arg, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %s", typeName(args[1]))
+		}
+		return f(s, arg), nil
+	}
+}
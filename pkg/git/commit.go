@@ -0,0 +1,127 @@
+package git
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// targetBranchVariables lists CI environment variables naming the target
+// branch of a pull or merge request, in lookup order
+var targetBranchVariables = []string{
+	"GITHUB_BASE_REF",                     // GitHub Actions
+	"CI_MERGE_REQUEST_TARGET_BRANCH_NAME", // GitLab CI
+}
+
+// readCommit fills in the commit details of info from the commit at hash.
+// Changed files are computed against the merge base with the target branch
+// when one is known, otherwise against the first parent. Missing history
+// (e.g., in shallow clones) leaves the changed files and lines empty. Lines
+// are only counted when lineStats is set.
+func readCommit(repo *git.Repository, hash plumbing.Hash, info *BranchInfo, lineStats bool) {
+	info.TargetBranch = targetBranch()
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return
+	}
+
+	info.CommitSHA = commit.Hash.String()
+	info.CommitMessage = strings.TrimSpace(commit.Message)
+	info.CommitAuthor = commit.Author.Email
+
+	base, err := diffBase(repo, commit, info.TargetBranch)
+	if err != nil {
+		return
+	}
+	if files, lines, err := changedFiles(base, commit, lineStats); err == nil {
+		info.ChangedFiles = files
+		info.LinesChanged = lines
+	}
+}
+
+// targetBranch returns the pull request target branch from the CI environment
+func targetBranch() string {
+	for _, name := range targetBranchVariables {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// diffBase returns the commit to compare against, or nil for a root commit
+func diffBase(repo *git.Repository, commit *object.Commit, target string) (*object.Commit, error) {
+	if target != "" {
+		for _, name := range []plumbing.ReferenceName{
+			plumbing.NewBranchReferenceName(target),
+			plumbing.NewRemoteReferenceName("origin", target),
+		} {
+			ref, err := repo.Reference(name, true)
+			if err != nil {
+				continue
+			}
+			targetCommit, err := repo.CommitObject(ref.Hash())
+			if err != nil {
+				continue
+			}
+			bases, err := commit.MergeBase(targetCommit)
+			if err == nil && len(bases) > 0 {
+				return bases[0], nil
+			}
+		}
+	}
+
+	if commit.NumParents() == 0 {
+		return nil, nil
+	}
+	return commit.Parent(0)
+}
+
+// changedFiles returns the sorted paths that differ between base and head and,
+// when lineStats is set, the number of lines added plus removed in them
+func changedFiles(base, head *object.Commit, lineStats bool) ([]string, int, error) {
+	headTree, err := head.Tree()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var baseTree *object.Tree
+	if base != nil {
+		if baseTree, err = base.Tree(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	changes, err := object.DiffTree(baseTree, headTree)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	files := make([]string, 0, len(changes))
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	if !lineStats {
+		return files, 0, nil
+	}
+
+	patch, err := changes.Patch()
+	if err != nil {
+		return nil, 0, err
+	}
+	lines := 0
+	for _, stat := range patch.Stats() {
+		lines += stat.Addition + stat.Deletion
+	}
+	return files, lines, nil
+}
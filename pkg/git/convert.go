@@ -0,0 +1,51 @@
+package git
+
+import "github.com/NadeeshaMedagama/branch_aware_ci/pkg/interfaces"
+
+// BranchInfoFromInterfaces converts the service branch type into a BranchInfo
+func BranchInfoFromInterfaces(b *interfaces.BranchInfo) *BranchInfo {
+	if b == nil {
+		return nil
+	}
+
+	return &BranchInfo{
+		Name:        b.Name,
+		ShortName:   b.ShortName,
+		Type:        b.Type,
+		Metadata:    b.Metadata,
+		IsProtected: b.IsProtected,
+
+		CommitSHA:     b.CommitSHA,
+		CommitMessage: b.CommitMessage,
+		CommitAuthor:  b.CommitAuthor,
+		TargetBranch:  b.TargetBranch,
+		ChangedFiles:  b.ChangedFiles,
+		LinesChanged:  b.LinesChanged,
+
+		Deleted: b.Deleted,
+	}
+}
+
+// ToInterfaces converts the BranchInfo into the service branch type
+func (b *BranchInfo) ToInterfaces() *interfaces.BranchInfo {
+	if b == nil {
+		return nil
+	}
+
+	return &interfaces.BranchInfo{
+		Name:        b.Name,
+		ShortName:   b.ShortName,
+		Type:        b.Type,
+		Metadata:    b.Metadata,
+		IsProtected: b.IsProtected,
+
+		CommitSHA:     b.CommitSHA,
+		CommitMessage: b.CommitMessage,
+		CommitAuthor:  b.CommitAuthor,
+		TargetBranch:  b.TargetBranch,
+		ChangedFiles:  b.ChangedFiles,
+		LinesChanged:  b.LinesChanged,
+
+		Deleted: b.Deleted,
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
 // BranchInfo contains information about the current Git branch
@@ -16,19 +18,45 @@ type BranchInfo struct {
 	Type        string            // Branch type (e.g., "feature", "hotfix", "release", "main")
 	Metadata    map[string]string // Extracted metadata from branch name
 	IsProtected bool              // Whether this is a protected branch
+
+	CommitSHA     string   // SHA of the commit being built
+	CommitMessage string   // Message of the commit being built
+	CommitAuthor  string   // Author email of the commit being built
+	TargetBranch  string   // Target branch of the pull/merge request, if any
+	ChangedFiles  []string // Files changed relative to the target branch or parent commit
+	LinesChanged  int      // Lines added plus lines removed in ChangedFiles (only counted WithLineStats)
+	CommitMissing string   // Why the commit of a named branch could not be read, if it could not
+
+	Deleted bool // Whether the branch was deleted (its environments are torn down)
 }
 
 // Detector handles Git branch detection
 type Detector struct {
-	repoPath string
+	repoPath  string
+	lineStats bool
+}
+
+// DetectorOption configures a Detector
+type DetectorOption func(*Detector)
+
+// WithLineStats makes the detector count the lines changed by the commit
+// in BranchInfo.LinesChanged, which needs a full diff of the changed files
+func WithLineStats() DetectorOption {
+	return func(d *Detector) {
+		d.lineStats = true
+	}
 }
 
 // NewDetector creates a new Git detector
-func NewDetector(repoPath string) *Detector {
+func NewDetector(repoPath string, opts ...DetectorOption) *Detector {
 	if repoPath == "" {
 		repoPath = "."
 	}
-	return &Detector{repoPath: repoPath}
+	d := &Detector{repoPath: repoPath}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
 // DetectBranch detects the current Git branch
@@ -53,10 +81,53 @@ func (d *Detector) DetectBranch() (*BranchInfo, error) {
 	// Determine branch type and extract metadata
 	d.parseBranchType(info)
 	d.checkProtected(info)
+	readCommit(repo, head.Hash(), info, d.lineStats)
 
 	return info, nil
 }
 
+// GetBranchInfo returns branch information for a branch name without
+// consulting the repository (e.g., to evaluate a branch other than HEAD)
+func (d *Detector) GetBranchInfo(branchName string) *BranchInfo {
+	info := &BranchInfo{
+		Name:      branchName,
+		ShortName: branchName,
+		Metadata:  make(map[string]string),
+	}
+
+	d.parseBranchType(info)
+	d.checkProtected(info)
+
+	return info
+}
+
+// ReadBranch returns branch information for a branch other than HEAD,
+// reading its commit from the local branch or, failing that, from origin.
+// When neither ref exists, CommitMissing records why and the commit fields
+// stay empty.
+func (d *Detector) ReadBranch(branchName string) *BranchInfo {
+	info := d.GetBranchInfo(branchName)
+
+	repo, err := git.PlainOpen(d.repoPath)
+	if err != nil {
+		info.CommitMissing = fmt.Sprintf("failed to open git repository: %v", err)
+		return info
+	}
+
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(branchName),
+		plumbing.NewRemoteReferenceName("origin", branchName),
+	} {
+		if ref, err := repo.Reference(name, true); err == nil {
+			readCommit(repo, ref.Hash(), info, d.lineStats)
+			return info
+		}
+	}
+
+	info.CommitMissing = fmt.Sprintf("branch %s not found in the repository", branchName)
+	return info
+}
+
 // parseBranchType determines the branch type and extracts metadata
 func (d *Detector) parseBranchType(info *BranchInfo) {
 	name := info.ShortName
@@ -105,6 +176,50 @@ func (d *Detector) checkProtected(info *BranchInfo) {
 	info.IsProtected = false
 }
 
+// ListBranches returns the names of all local and remote-tracking branches.
+// Remote branches are reported without their remote prefix (e.g., "origin/develop"
+// becomes "develop") and duplicates are removed.
+func (d *Detector) ListBranches() ([]string, error) {
+	repo, err := git.PlainOpen(d.repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+	defer refs.Close()
+
+	seen := make(map[string]bool)
+	var branches []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		var name string
+		switch {
+		case ref.Name().IsBranch():
+			name = ref.Name().Short()
+		case ref.Name().IsRemote():
+			// Strip the remote name ("origin/develop" -> "develop")
+			short := ref.Name().Short()
+			if idx := strings.Index(short, "/"); idx >= 0 {
+				name = short[idx+1:]
+			}
+		}
+		if name == "" || name == "HEAD" || seen[name] {
+			return nil
+		}
+		seen[name] = true
+		branches = append(branches, name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate references: %w", err)
+	}
+
+	sort.Strings(branches)
+	return branches, nil
+}
+
 // GetRepositoryRoot returns the root path of the Git repository
 func (d *Detector) GetRepositoryRoot() (string, error) {
 	repo, err := git.PlainOpen(d.repoPath)
@@ -1,12 +1,16 @@
 package git
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
@@ -152,3 +156,231 @@ func TestMetadataExtraction(t *testing.T) {
 		t.Errorf("Expected suffix, got %s", suffix)
 	}
 }
+
+func TestListBranches(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "branch-aware-ci-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	repo, err := git.PlainInit(tmpDir, false)
+	if err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "test.txt"), []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, err := worktree.Add("test.txt"); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+	hash, err := worktree.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	// One local branch and one remote-tracking branch
+	refs := []*plumbing.Reference{
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature/login"), hash),
+		plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "develop"), hash),
+		plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "feature/login"), hash),
+	}
+	for _, ref := range refs {
+		if err := repo.Storer.SetReference(ref); err != nil {
+			t.Fatalf("Failed to create reference: %v", err)
+		}
+	}
+
+	branches, err := NewDetector(tmpDir).ListBranches()
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+
+	head, _ := repo.Head()
+	expected := []string{"develop", "feature/login", head.Name().Short()}
+	sort.Strings(expected)
+	if strings.Join(branches, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected branches %v, got %v", expected, branches)
+	}
+}
+
+func TestDetectBranchCommitInfo(t *testing.T) {
+	t.Setenv("GITHUB_BASE_REF", "")
+	t.Setenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME", "")
+
+	tmpDir := t.TempDir()
+	repo, err := git.PlainInit(tmpDir, false)
+	if err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+
+	commit := func(message string, files ...string) plumbing.Hash {
+		for _, name := range files {
+			path := filepath.Join(tmpDir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(message), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			if _, err := worktree.Add(name); err != nil {
+				t.Fatalf("Failed to add file: %v", err)
+			}
+		}
+		hash, err := worktree.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		return hash
+	}
+
+	base := commit("Initial commit", "README.md")
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("develop"), base)); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	commit("Add service", "src/service.go")
+	head := commit("Add migration", "migrations/001.sql")
+
+	info, err := NewDetector(tmpDir, WithLineStats()).DetectBranch()
+	if err != nil {
+		t.Fatalf("Failed to detect branch: %v", err)
+	}
+	if info.CommitSHA != head.String() || info.CommitMessage != "Add migration" || info.CommitAuthor != "test@example.com" {
+		t.Errorf("Unexpected commit info: %s %q %s", info.CommitSHA, info.CommitMessage, info.CommitAuthor)
+	}
+	if got := strings.Join(info.ChangedFiles, ","); got != "migrations/001.sql" {
+		t.Errorf("Expected files changed by the last commit, got %s", got)
+	}
+	if info.LinesChanged != 1 {
+		t.Errorf("Expected 1 line changed by the last commit, got %d", info.LinesChanged)
+	}
+
+	// Lines are only counted when asked for
+	info, err = NewDetector(tmpDir).DetectBranch()
+	if err != nil {
+		t.Fatalf("Failed to detect branch: %v", err)
+	}
+	if len(info.ChangedFiles) != 1 || info.LinesChanged != 0 {
+		t.Errorf("Expected changed files without line stats, got %v %d", info.ChangedFiles, info.LinesChanged)
+	}
+
+	// A named branch is read from its ref rather than HEAD
+	info = NewDetector(tmpDir).ReadBranch("develop")
+	if info.CommitSHA != base.String() || info.CommitMessage != "Initial commit" || info.CommitMissing != "" {
+		t.Errorf("Expected the develop commit, got %s %q (missing: %q)", info.CommitSHA, info.CommitMessage, info.CommitMissing)
+	}
+	info = NewDetector(tmpDir).ReadBranch("feature/gone")
+	if info.Type != "feature" || info.CommitSHA != "" || info.CommitMissing == "" {
+		t.Errorf("Expected a feature branch without commit data, got %s %q (missing: %q)", info.Type, info.CommitSHA, info.CommitMissing)
+	}
+
+	// With a target branch, files are compared against the merge base
+	t.Setenv("GITHUB_BASE_REF", "develop")
+	info, err = NewDetector(tmpDir, WithLineStats()).DetectBranch()
+	if err != nil {
+		t.Fatalf("Failed to detect branch: %v", err)
+	}
+	if info.TargetBranch != "develop" {
+		t.Errorf("Expected target branch develop, got %q", info.TargetBranch)
+	}
+	if got := strings.Join(info.ChangedFiles, ","); got != "migrations/001.sql,src/service.go" {
+		t.Errorf("Expected files changed since develop, got %s", got)
+	}
+	if info.LinesChanged != 2 {
+		t.Errorf("Expected 2 lines changed since develop, got %d", info.LinesChanged)
+	}
+}
+
+func TestDeletedBranchFromEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(path, []byte(`{"ref": "feature/user-auth", "ref_type": "branch"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_EVENT_PATH", path)
+
+	t.Setenv("GITHUB_EVENT_NAME", "push")
+	if _, ok := DeletedBranchFromEvent(); ok {
+		t.Error("Expected no deleted branch for a push event")
+	}
+
+	t.Setenv("GITHUB_EVENT_NAME", "delete")
+	if name, ok := DeletedBranchFromEvent(); !ok || name != "feature/user-auth" {
+		t.Errorf("Expected feature/user-auth, got %q (%v)", name, ok)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"ref": "v1.0.0", "ref_type": "tag"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := DeletedBranchFromEvent(); ok {
+		t.Error("Expected no deleted branch for a deleted tag")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo, err := git.PlainInit(tmpDir, false)
+	if err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+
+	commit := func(content string) {
+		path := filepath.Join(tmpDir, ".github", "branchci.yml")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := worktree.Add(".github/branchci.yml"); err != nil {
+			t.Fatalf("Failed to add file: %v", err)
+		}
+		if _, err := worktree.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()},
+		}); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+	}
+	commit("version: 1")
+	commit("version: 2")
+
+	// The working tree is not consulted
+	if err := os.WriteFile(filepath.Join(tmpDir, ".github", "branchci.yml"), []byte("version: 3"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	detector := NewDetector(tmpDir)
+	for revision, want := range map[string]string{"HEAD": "version: 2", "HEAD~1": "version: 1"} {
+		path, data, err := detector.ReadFile(revision, ".branchci.yml", ".github/branchci.yml")
+		if err != nil {
+			t.Fatalf("ReadFile(%s) failed: %v", revision, err)
+		}
+		if path != ".github/branchci.yml" || string(data) != want {
+			t.Errorf("ReadFile(%s): expected %q from .github/branchci.yml, got %q from %s", revision, want, data, path)
+		}
+	}
+
+	if _, _, err := detector.ReadFile("HEAD", ".branchci.yml"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected ErrFileNotFound, got %v", err)
+	}
+	if _, _, err := detector.ReadFile("no-such-branch", ".branchci.yml"); err == nil || errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected an error for an unknown revision, got %v", err)
+	}
+}
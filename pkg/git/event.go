@@ -0,0 +1,29 @@
+package git
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// DeletedBranchFromEvent returns the branch deleted by the GitHub Actions
+// "delete" event that triggered the run, if there is one
+func DeletedBranchFromEvent() (string, bool) {
+	if os.Getenv("GITHUB_EVENT_NAME") != "delete" {
+		return "", false
+	}
+
+	data, err := os.ReadFile(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		return "", false
+	}
+
+	var event struct {
+		Ref     string `json:"ref"`
+		RefType string `json:"ref_type"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil || event.RefType != "branch" || event.Ref == "" {
+		return "", false
+	}
+	return strings.TrimPrefix(event.Ref, "refs/heads/"), true
+}
@@ -0,0 +1,58 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrFileNotFound is returned by ReadFile when none of the paths exists at the revision
+var ErrFileNotFound = errors.New("file not found at revision")
+
+// ReadFile reads the first of paths that exists in the tree of a revision
+// (a branch, tag, commit SHA or expression such as "HEAD~1") from the object
+// store, without checking it out. It returns the path that was read.
+func (d *Detector) ReadFile(revision string, paths ...string) (string, []byte, error) {
+	repo, err := git.PlainOpen(d.repoPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve revision %s: %w", revision, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read commit %s: %w", revision, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read tree of %s: %w", revision, err)
+	}
+
+	for _, path := range paths {
+		file, err := tree.File(path)
+		if errors.Is(err, object.ErrFileNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s at %s: %w", path, revision, err)
+		}
+		reader, err := file.Reader()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s at %s: %w", path, revision, err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s at %s: %w", path, revision, err)
+		}
+		return path, data, nil
+	}
+	return "", nil, ErrFileNotFound
+}
@@ -0,0 +1,124 @@
+// Package history records which commits were deployed to which environments.
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultPath is the state file used when the configuration does not name one
+const DefaultPath = ".branchci/deployments.json"
+
+// Record is a deployment of a commit to an environment
+type Record struct {
+	Environment string    `json:"environment"`
+	CommitSHA   string    `json:"commit_sha"`
+	Branch      string    `json:"branch,omitempty"`
+	DeployedAt  time.Time `json:"deployed_at"`
+}
+
+// History is a set of deployment records
+type History struct {
+	Deployments []Record `json:"deployments"`
+}
+
+// decisionRecord holds the fields of a decision relevant to deployments
+type decisionRecord struct {
+	BranchName   string `json:"branch_name"`
+	Environment  string `json:"environment"`
+	CommitSHA    string `json:"commit_sha"`
+	ShouldDeploy bool   `json:"should_deploy"`
+}
+
+// Load reads a history file. The file is either a state file written by Save
+// or a decision history: a stream of JSON decisions such as the output of
+// repeated "-format json" runs, where every decision with should_deploy set
+// counts as a deployment. A missing file yields an empty history.
+func Load(path string) (*History, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &History{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployment history: %w", err)
+	}
+
+	h := &History{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var entry struct {
+			Deployments []Record `json:"deployments"`
+			decisionRecord
+		}
+		if err := decoder.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse deployment history %s: %w", path, err)
+		}
+
+		h.Deployments = append(h.Deployments, entry.Deployments...)
+		if d := entry.decisionRecord; d.ShouldDeploy && d.Environment != "" && d.CommitSHA != "" {
+			h.Deployments = append(h.Deployments, Record{
+				Environment: d.Environment,
+				CommitSHA:   d.CommitSHA,
+				Branch:      d.BranchName,
+			})
+		}
+	}
+
+	return h, nil
+}
+
+// Save writes the history as a state file
+func (h *History) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal deployment history: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write deployment history: %w", err)
+	}
+	return nil
+}
+
+// Add records a deployment
+func (h *History) Add(record Record) {
+	h.Deployments = append(h.Deployments, record)
+}
+
+// Deployed reports whether the commit was deployed to the environment.
+// Abbreviated SHAs of at least seven characters match full ones.
+func (h *History) Deployed(environment, sha string) bool {
+	for _, r := range h.Deployments {
+		if r.Environment == environment && sameCommit(r.CommitSHA, sha) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameCommit compares commit SHAs, allowing either to be abbreviated
+func sameCommit(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) < 7 {
+		return a == b
+	}
+	return strings.HasPrefix(strings.ToLower(b), strings.ToLower(a))
+}
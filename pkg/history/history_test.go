@@ -0,0 +1,93 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadMissingFile(t *testing.T) {
+	h, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(h.Deployments) != 0 {
+		t.Errorf("Expected an empty history, got %v", h.Deployments)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "deployments.json")
+
+	h := &History{}
+	h.Add(Record{
+		Environment: "staging",
+		CommitSHA:   "0123456789abcdef0123456789abcdef01234567",
+		Branch:      "main",
+		DeployedAt:  time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	})
+	if err := h.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Deployments) != 1 || loaded.Deployments[0] != h.Deployments[0] {
+		t.Errorf("Expected %v, got %v", h.Deployments, loaded.Deployments)
+	}
+}
+
+func TestLoadDecisionHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.json")
+	data := `{"branch_name": "develop", "environment": "staging", "should_deploy": true, "commit_sha": "abc1234def"}
+{"branch_name": "feature/x", "environment": "development", "should_deploy": false, "commit_sha": "fff0000"}
+{"branch_name": "main", "environment": "production", "should_deploy": true}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(h.Deployments) != 1 || h.Deployments[0].Environment != "staging" || h.Deployments[0].Branch != "develop" {
+		t.Errorf("Expected only the staging deployment, got %v", h.Deployments)
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for a malformed history")
+	}
+}
+
+func TestDeployed(t *testing.T) {
+	h := &History{Deployments: []Record{
+		{Environment: "staging", CommitSHA: "0123456789abcdef0123456789abcdef01234567"},
+	}}
+
+	tests := []struct {
+		environment string
+		sha         string
+		want        bool
+	}{
+		{"staging", "0123456789abcdef0123456789abcdef01234567", true},
+		{"staging", "0123456", true},
+		{"staging", "0123456789ABCDEF", true},
+		{"staging", "012345", false},
+		{"staging", "7654321", false},
+		{"production", "0123456", false},
+		{"staging", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := h.Deployed(tt.environment, tt.sha); got != tt.want {
+			t.Errorf("Deployed(%q, %q) = %v, want %v", tt.environment, tt.sha, got, tt.want)
+		}
+	}
+}
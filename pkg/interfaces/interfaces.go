@@ -11,19 +11,129 @@ type BranchInfo struct {
 	Type        string
 	Metadata    map[string]string
 	IsProtected bool
+
+	CommitSHA     string
+	CommitMessage string
+	CommitAuthor  string
+	TargetBranch  string
+	ChangedFiles  []string
+	LinesChanged  int
+
+	Deleted bool
 }
 
 // Decision represents a CI/CD decision
 type Decision struct {
 	BranchName       string
 	BranchType       string
+	CommitSHA        string
 	Environment      string
 	ShouldDeploy     bool
 	RequiresApproval bool
 	Actions          []string
 	Variables        map[string]string
 	Warnings         []string
+	Findings         []Finding
 	Metadata         map[string]string
+	Approval         *Approval
+	Risk             *Risk
+	Ephemeral        bool
+	Teardown         bool
+	Plan             []PlanStage
+	Environments     []EnvironmentDecision
+	Trace            *DecisionTrace
+}
+
+// EnvironmentDecision is the decision for one environment of a mapping that
+// targets several environments
+type EnvironmentDecision struct {
+	Environment      string
+	ShouldDeploy     bool
+	RequiresApproval bool
+	Approval         *Approval
+	Risk             *Risk
+	Ephemeral        bool
+	Teardown         bool
+	Actions          []string
+	Variables        map[string]string
+	Warnings         []string
+	Findings         []Finding
+}
+
+// Risk is the risk score of a decision and the factors that make it up
+type Risk struct {
+	Score   int
+	Level   string
+	Factors []RiskFactor
+}
+
+// RiskFactor is one contribution to a risk score
+type RiskFactor struct {
+	Factor string
+	Points int
+	Reason string
+}
+
+// PlanStage is a group of actions that can run once every earlier stage is done
+type PlanStage struct {
+	Actions []PlannedAction
+}
+
+// PlannedAction is an action of a plan stage with its catalog settings
+type PlannedAction struct {
+	Name   string
+	Needs  []string
+	Params map[string]string
+}
+
+// Finding is a message about a decision with a severity and a stable code
+type Finding struct {
+	Severity string
+	Code     string
+	Message  string
+}
+
+// DecisionTrace records how a decision was reached
+type DecisionTrace struct {
+	Mappings      []MappingTrace
+	Winner        *MappingTrace
+	AllowedBranch *AllowedTrace
+	Changes       []ChangeTrace
+	Variables     []VariableTrace
+}
+
+// MappingTrace records whether a branch mapping matched
+type MappingTrace struct {
+	Index       int
+	Pattern     string
+	Environment string
+	Priority    int
+	Specificity int
+	Matched     bool
+}
+
+// AllowedTrace records the allowed-branch check for the chosen environment
+type AllowedTrace struct {
+	Environment string
+	Patterns    []string
+	Allowed     bool
+	MatchedBy   string
+}
+
+// ChangeTrace records a configuration source changing a decision field
+type ChangeTrace struct {
+	Source string
+	Field  string
+	From   string
+	To     string
+	Reason string
+}
+
+// VariableTrace records where a decision variable came from
+type VariableTrace struct {
+	Name   string
+	Value  string
+	Source string
 }
 
 // Config represents the application configuration
@@ -31,6 +141,57 @@ type Config struct {
 	Environments   map[string]EnvironmentConfig
 	BranchMappings []BranchMapping
 	Policies       PolicyConfig
+	FreezeWindows  []FreezeWindow
+	Promotion      PromotionConfig
+	Risk           *RiskConfig
+	Actions        map[string]ActionConfig
+}
+
+// ActionConfig declares a known action in the action catalog
+type ActionConfig struct {
+	Description string
+	Needs       []string
+	Params      map[string]string
+}
+
+// RiskConfig defines how a decision's risk score is computed
+type RiskConfig struct {
+	BranchTypes    map[string]int
+	Environments   map[string]int
+	Changes        RiskChanges
+	SensitivePaths []RiskPath
+	OffHours       *RiskOffHours
+	Thresholds     []RiskThreshold
+}
+
+// RiskChanges weighs the size of the change
+type RiskChanges struct {
+	PerFile         int
+	PerHundredLines int
+	Max             int
+}
+
+// RiskPath adds Weight when a changed file matches Pattern
+type RiskPath struct {
+	Pattern string
+	Weight  int
+}
+
+// RiskOffHours adds Weight outside working hours
+type RiskOffHours struct {
+	Start    int
+	End      int
+	Weekends bool
+	Timezone string
+	Weight   int
+}
+
+// RiskThreshold applies effects to decisions whose score reaches Score
+type RiskThreshold struct {
+	Name            string
+	Score           int
+	RequireApproval bool
+	AddActions      []string
 }
 
 // EnvironmentConfig defines settings for a specific environment
@@ -40,14 +201,33 @@ type EnvironmentConfig struct {
 	AllowedBranches  []string
 	Variables        map[string]string
 	NotifyOnDeploy   bool
+	Approval         *Approval
+	Template         *EnvironmentTemplate
+}
+
+// EnvironmentTemplate makes an environment a template for ephemeral
+// per-branch environments
+type EnvironmentTemplate struct {
+	Name      string
+	MaxLength int
+	URL       string
+}
+
+// Approval describes who must approve a deployment
+type Approval struct {
+	Required          int
+	Teams             []string
+	Users             []string
+	AllowSelfApproval bool
 }
 
 // BranchMapping maps branch patterns to environments
 type BranchMapping struct {
-	Pattern     string
-	Environment string
-	Actions     []string
-	Priority    int
+	Pattern      string
+	Environment  string
+	Environments []string
+	Actions      []string
+	Priority     int
 }
 
 // PolicyConfig defines CI/CD policies
@@ -56,6 +236,47 @@ type PolicyConfig struct {
 	RequireCodeReview     bool
 	BlockedBranchPatterns []string
 	AutoDeployBranches    []string
+	Rules                 []Rule
+	Enforce               bool
+}
+
+// Rule applies effects to a decision when its condition holds
+type Rule struct {
+	Name            string
+	When            string
+	SetEnvironment  string
+	AddActions      []string
+	RemoveActions   []string
+	RequireApproval bool
+	Block           bool
+	SetVariables    map[string]string
+	Warn            string
+	Severity        string
+}
+
+// FreezeWindow blocks deployments to some environments during a period of time
+type FreezeWindow struct {
+	Name           string
+	Environments   []string
+	Cron           string
+	Duration       string
+	Start          string
+	End            string
+	Timezone       string
+	ExemptBranches []string
+}
+
+// PromotionConfig defines environment promotion chains
+type PromotionConfig struct {
+	StateFile string
+	Chains    []PromotionChain
+}
+
+// PromotionChain orders the environments a commit moves through
+type PromotionChain struct {
+	Name         string
+	Environments []string
+	OnIneligible string
 }
 
 // IBranchDetector defines the interface for branch detection
@@ -70,6 +291,11 @@ type IPolicyEngine interface {
 	ValidatePolicy(ctx context.Context, config *Config) (bool, []string, error)
 }
 
+// IPolicyExplainer is implemented by policy engines that can record a decision trace
+type IPolicyExplainer interface {
+	Explain(ctx context.Context, branchInfo *BranchInfo, config *Config) (*Decision, error)
+}
+
 // IConfigManager defines the interface for configuration management
 type IConfigManager interface {
 	GetConfig(ctx context.Context, configPath string) (*Config, error)
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -15,12 +18,23 @@ import (
 type Format string
 
 const (
-	FormatJSON         Format = "json"
-	FormatYAML         Format = "yaml"
-	FormatEnv          Format = "env"
-	FormatGitHubEnv    Format = "github-env"
-	FormatGitHubOutput Format = "github-output"
-	FormatHuman        Format = "human"
+	FormatJSON              Format = "json"
+	FormatYAML              Format = "yaml"
+	FormatEnv               Format = "env"
+	FormatShell             Format = "shell"
+	FormatGitHubEnv         Format = "github-env"
+	FormatGitHubOutput      Format = "github-output"
+	FormatGitHubSummary     Format = "github-summary"
+	FormatHuman             Format = "human"
+	FormatPlan              Format = "plan"
+	FormatGitLabDotenv      Format = "gitlab-dotenv"
+	FormatGitLabRules       Format = "gitlab-rules"
+	FormatAzure             Format = "azure"
+	FormatJenkins           Format = "jenkins"
+	FormatBuildkite         Format = "buildkite"
+	FormatBuildkitePipeline Format = "buildkite-pipeline"
+	FormatCircleCI          Format = "circleci"
+	FormatAuto              Format = "auto" // The format of the detected CI provider
 )
 
 // Formatter handles output formatting
@@ -41,13 +55,35 @@ func (f *Formatter) Format(decision *policy.Decision) (string, error) {
 	case FormatYAML:
 		return f.formatYAML(decision)
 	case FormatEnv:
-		return f.formatEnv(decision), nil
+		return f.formatEnv(decision)
+	case FormatShell:
+		return f.formatShell(decision)
 	case FormatGitHubEnv:
 		return f.formatGitHubEnv(decision)
 	case FormatGitHubOutput:
 		return f.formatGitHubOutput(decision)
+	case FormatGitHubSummary:
+		return f.formatGitHubSummary(decision)
 	case FormatHuman:
 		return f.formatHuman(decision), nil
+	case FormatPlan:
+		return f.formatPlan(decision), nil
+	case FormatGitLabDotenv:
+		return f.formatGitLabDotenv(decision)
+	case FormatGitLabRules:
+		return f.formatGitLabRules(decision)
+	case FormatAzure:
+		return f.formatAzure(decision)
+	case FormatJenkins:
+		return f.formatJenkins(decision), nil
+	case FormatBuildkite:
+		return f.formatBuildkite(decision), nil
+	case FormatBuildkitePipeline:
+		return f.formatBuildkitePipeline(decision)
+	case FormatCircleCI:
+		return f.formatCircleCI(decision)
+	case FormatAuto:
+		return f.formatAuto(decision)
 	default:
 		return "", fmt.Errorf("unsupported format: %s", f.format)
 	}
@@ -71,25 +107,117 @@ func (f *Formatter) formatYAML(decision *policy.Decision) (string, error) {
 	return string(data), nil
 }
 
-// formatEnv formats as environment variables
-func (f *Formatter) formatEnv(decision *policy.Decision) string {
-	var lines []string
+// envVar is a named value written by the environment-variable formats
+type envVar struct {
+	name  string
+	value string
+}
+
+// envVars returns the variables the environment-variable formats write: the
+// decision fields followed by the decision's variables in sorted order
+func envVars(decision *policy.Decision) []envVar {
+	var vars []envVar
+	add := func(name, value string) {
+		vars = append(vars, envVar{name: name, value: value})
+	}
 
-	lines = append(lines, fmt.Sprintf("BRANCH_NAME=%s", decision.BranchName))
-	lines = append(lines, fmt.Sprintf("BRANCH_TYPE=%s", decision.BranchType))
-	lines = append(lines, fmt.Sprintf("ENVIRONMENT=%s", decision.Environment))
-	lines = append(lines, fmt.Sprintf("SHOULD_DEPLOY=%t", decision.ShouldDeploy))
-	lines = append(lines, fmt.Sprintf("REQUIRES_APPROVAL=%t", decision.RequiresApproval))
+	add("BRANCH_NAME", decision.BranchName)
+	add("BRANCH_TYPE", decision.BranchType)
+	add("ENVIRONMENT", decision.Environment)
+	add("SHOULD_DEPLOY", strconv.FormatBool(decision.ShouldDeploy))
+	add("REQUIRES_APPROVAL", strconv.FormatBool(decision.RequiresApproval))
+	if decision.Ephemeral {
+		add("EPHEMERAL", "true")
+		add("TEARDOWN", strconv.FormatBool(decision.Teardown))
+	}
+	if decision.Approval != nil {
+		add("REQUIRED_APPROVALS", strconv.Itoa(decision.Approval.Required))
+		add("REQUIRED_REVIEWERS", strings.Join(decision.Approval.Reviewers(), ","))
+	}
+	if decision.Risk != nil {
+		add("RISK_SCORE", strconv.Itoa(decision.Risk.Score))
+		add("RISK_LEVEL", decision.Risk.Level)
+	}
 
 	if len(decision.Actions) > 0 {
-		lines = append(lines, fmt.Sprintf("ACTIONS=%s", strings.Join(decision.Actions, ",")))
+		add("ACTIONS", strings.Join(decision.Actions, ","))
 	}
 
-	for k, v := range decision.Variables {
-		lines = append(lines, fmt.Sprintf("%s=%s", k, v))
+	if len(decision.Environments) > 0 {
+		add("ENVIRONMENTS", strings.Join(environmentNames(decision), ","))
 	}
 
-	return strings.Join(lines, "\n")
+	for _, k := range sortedKeys(decision.Variables) {
+		add(k, decision.Variables[k])
+	}
+
+	return vars
+}
+
+// plainShellValue matches values a POSIX shell reads literally without quotes
+var plainShellValue = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]*$`)
+
+// formatEnv formats as environment variables that a POSIX shell can source.
+// Values with characters the shell would interpret are quoted.
+func (f *Formatter) formatEnv(decision *policy.Decision) (string, error) {
+	var lines []string
+	for _, v := range envVars(decision) {
+		if err := validateShellName(v.name); err != nil {
+			return "", err
+		}
+		value := v.value
+		if !plainShellValue.MatchString(value) {
+			value = shellQuote(value)
+		}
+		lines = append(lines, fmt.Sprintf("%s=%s", v.name, value))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatShell formats as export statements for eval or source
+func (f *Formatter) formatShell(decision *policy.Decision) (string, error) {
+	var lines []string
+	for _, v := range envVars(decision) {
+		if err := validateShellName(v.name); err != nil {
+			return "", err
+		}
+		lines = append(lines, fmt.Sprintf("export %s=%s", v.name, shellQuote(v.value)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// validateShellName returns an error for a variable name a POSIX shell rejects
+func validateShellName(name string) error {
+	if !shellVariableName.MatchString(name) {
+		return fmt.Errorf("variable %q: shell variable names may only contain letters, digits and underscores and may not start with a digit", name)
+	}
+	return nil
+}
+
+// githubFileEntry formats a name and value for the GITHUB_ENV and
+// GITHUB_OUTPUT files. Values with line breaks use the heredoc syntax, with a
+// delimiter that is not a line of the value.
+func githubFileEntry(name, value string) string {
+	if !strings.ContainsAny(value, "\r\n") {
+		return fmt.Sprintf("%s=%s", name, value)
+	}
+
+	delimiter := "BRANCHCI_EOF"
+	valueLines := strings.Split(strings.ReplaceAll(value, "\r\n", "\n"), "\n")
+	for i := 1; containsLine(valueLines, delimiter); i++ {
+		delimiter = fmt.Sprintf("BRANCHCI_EOF_%d", i)
+	}
+	return fmt.Sprintf("%s<<%s\n%s\n%s", name, delimiter, value, delimiter)
+}
+
+// containsLine reports whether one of lines is line, ignoring a trailing \r
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if strings.TrimSuffix(l, "\r") == line {
+			return true
+		}
+	}
+	return false
 }
 
 // formatGitHubEnv formats for GitHub Actions environment file
@@ -105,35 +233,106 @@ func (f *Formatter) formatGitHubEnv(decision *policy.Decision) (string, error) {
 	}
 	defer file.Close()
 
-	envContent := f.formatEnv(decision)
-	if _, err := file.WriteString(envContent + "\n"); err != nil {
+	var lines []string
+	for _, v := range envVars(decision) {
+		if err := validateShellName(v.name); err != nil {
+			return "", err
+		}
+		lines = append(lines, githubFileEntry(v.name, v.value))
+	}
+	if _, err := file.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
 		return "", fmt.Errorf("failed to write to GITHUB_ENV: %w", err)
 	}
 
 	return "Environment variables written to $GITHUB_ENV", nil
 }
 
-// formatGitHubOutput formats for GitHub Actions output
+// defaultOutputPrefix is put before the names of the decision's variables in
+// GitHub Actions outputs, unless BRANCHCI_OUTPUT_PREFIX sets another one
+const defaultOutputPrefix = "var_"
+
+// githubOutputName matches the output names GitHub Actions accepts
+var githubOutputName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// formatGitHubOutput formats for GitHub Actions output: the decision fields,
+// its warnings, its metadata (ticket, suffix), the whole decision as JSON and
+// each of its variables under a prefix (var_ unless BRANCHCI_OUTPUT_PREFIX
+// sets another one, possibly empty)
 func (f *Formatter) formatGitHubOutput(decision *policy.Decision) (string, error) {
 	outputFile := os.Getenv("GITHUB_OUTPUT")
 	if outputFile == "" {
 		return "", fmt.Errorf("GITHUB_OUTPUT not set")
 	}
 
+	// Every environment as a JSON array, for use as a job matrix
+	environments, err := json.Marshal(decision.Targets())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal environments: %w", err)
+	}
+	warnings, err := json.Marshal(append([]string{}, decision.Warnings...))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal warnings: %w", err)
+	}
+	decisionJSON, err := json.Marshal(decision)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal decision: %w", err)
+	}
+
+	outputs := []envVar{
+		{"branch_name", decision.BranchName},
+		{"branch_type", decision.BranchType},
+		{"environment", decision.Environment},
+		{"should_deploy", strconv.FormatBool(decision.ShouldDeploy)},
+		{"requires_approval", strconv.FormatBool(decision.RequiresApproval)},
+		{"ephemeral", strconv.FormatBool(decision.Ephemeral)},
+		{"teardown", strconv.FormatBool(decision.Teardown)},
+		{"required_approvals", strconv.Itoa(requiredApprovals(decision.Approval))},
+		{"required_reviewers", strings.Join(decision.Approval.Reviewers(), ",")},
+		{"risk_score", riskScore(decision.Risk)},
+		{"risk_level", riskLevel(decision.Risk)},
+		{"actions", strings.Join(decision.Actions, ",")},
+		{"environments", string(environments)},
+		{"warnings", string(warnings)},
+	}
+
+	// Metadata keys are always set, so workflows can test them for emptiness
+	metadata := map[string]string{"ticket": "", "suffix": ""}
+	for k, v := range decision.Metadata {
+		metadata[k] = v
+	}
+	for _, k := range sortedKeys(metadata) {
+		outputs = append(outputs, envVar{k, metadata[k]})
+	}
+	outputs = append(outputs, envVar{"decision_json", string(decisionJSON)})
+
+	prefix, ok := os.LookupEnv("BRANCHCI_OUTPUT_PREFIX")
+	if !ok {
+		prefix = defaultOutputPrefix
+	}
+	for _, k := range sortedKeys(decision.Variables) {
+		outputs = append(outputs, envVar{prefix + k, decision.Variables[k]})
+	}
+
+	// Output names are case-insensitive, so a variable may not shadow another output
+	var lines []string
+	seen := make(map[string]bool)
+	for _, o := range outputs {
+		if !githubOutputName.MatchString(o.name) {
+			return "", fmt.Errorf("output %q: GitHub output names may only contain letters, digits, '-' and '_' and must start with a letter or '_'", o.name)
+		}
+		if seen[strings.ToLower(o.name)] {
+			return "", fmt.Errorf("output %q is set twice; set BRANCHCI_OUTPUT_PREFIX to prefix variable names", o.name)
+		}
+		seen[strings.ToLower(o.name)] = true
+		lines = append(lines, githubFileEntry(o.name, o.value))
+	}
+
 	file, err := os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return "", fmt.Errorf("failed to open GITHUB_OUTPUT file: %w", err)
 	}
 	defer file.Close()
 
-	var lines []string
-	lines = append(lines, fmt.Sprintf("branch_name=%s", decision.BranchName))
-	lines = append(lines, fmt.Sprintf("branch_type=%s", decision.BranchType))
-	lines = append(lines, fmt.Sprintf("environment=%s", decision.Environment))
-	lines = append(lines, fmt.Sprintf("should_deploy=%t", decision.ShouldDeploy))
-	lines = append(lines, fmt.Sprintf("requires_approval=%t", decision.RequiresApproval))
-	lines = append(lines, fmt.Sprintf("actions=%s", strings.Join(decision.Actions, ",")))
-
 	output := strings.Join(lines, "\n") + "\n"
 	if _, err := file.WriteString(output); err != nil {
 		return "", fmt.Errorf("failed to write to GITHUB_OUTPUT: %w", err)
@@ -150,38 +349,85 @@ func (f *Formatter) formatHuman(decision *policy.Decision) string {
 	lines = append(lines, "==================")
 	lines = append(lines, fmt.Sprintf("Branch:      %s", decision.BranchName))
 	lines = append(lines, fmt.Sprintf("Type:        %s", decision.BranchType))
-	lines = append(lines, fmt.Sprintf("Environment: %s", decision.Environment))
+	if decision.Ephemeral {
+		lines = append(lines, fmt.Sprintf("Environment: %s (ephemeral)", decision.Environment))
+	} else {
+		lines = append(lines, fmt.Sprintf("Environment: %s", decision.Environment))
+	}
 	lines = append(lines, "")
 
 	lines = append(lines, "📋 CI/CD Decision")
 	lines = append(lines, "==================")
 
+	if decision.Teardown {
+		lines = append(lines, "🧹 Teardown: Yes")
+	}
 	if decision.ShouldDeploy {
 		lines = append(lines, "✅ Should Deploy: Yes")
 	} else {
 		lines = append(lines, "❌ Should Deploy: No")
 	}
 
-	if decision.RequiresApproval {
+	if decision.RequiresApproval && decision.Approval != nil {
+		lines = append(lines, fmt.Sprintf("⚠️  Requires Approval: Yes (%s)", decision.Approval))
+	} else if decision.RequiresApproval {
 		lines = append(lines, "⚠️  Requires Approval: Yes")
 	} else {
 		lines = append(lines, "✓  Requires Approval: No")
 	}
 
+	if decision.Risk != nil {
+		lines = append(lines, fmt.Sprintf("🎲 Risk Score: %s", decision.Risk))
+		for _, factor := range decision.Risk.Factors {
+			lines = append(lines, fmt.Sprintf("    %+d %s", factor.Points, factor.Reason))
+		}
+	}
+
 	if len(decision.Actions) > 0 {
 		lines = append(lines, fmt.Sprintf("Actions:     %s", strings.Join(decision.Actions, ", ")))
 	}
 
+	if len(decision.Environments) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "🌍 Environments")
+		lines = append(lines, "===============")
+		for _, env := range decision.Environments {
+			deploy := "deploy"
+			if !env.ShouldDeploy {
+				deploy = "no deploy"
+			}
+			if env.Approval != nil {
+				deploy += ", requires " + env.Approval.String()
+			} else if env.RequiresApproval {
+				deploy += ", requires approval"
+			}
+			lines = append(lines, fmt.Sprintf("- %s (%s)", env.Environment, deploy))
+			for _, k := range sortedKeys(env.Variables) {
+				lines = append(lines, fmt.Sprintf("    %s=%s", k, env.Variables[k]))
+			}
+			for _, warning := range env.Warnings {
+				lines = append(lines, fmt.Sprintf("    ⚠️  %s", warning))
+			}
+		}
+	}
+
 	if len(decision.Variables) > 0 {
 		lines = append(lines, "")
 		lines = append(lines, "🔧 Variables")
 		lines = append(lines, "============")
-		for k, v := range decision.Variables {
-			lines = append(lines, fmt.Sprintf("%s=%s", k, v))
+		for _, k := range sortedKeys(decision.Variables) {
+			lines = append(lines, fmt.Sprintf("%s=%s", k, decision.Variables[k]))
 		}
 	}
 
-	if len(decision.Warnings) > 0 {
+	if len(decision.Findings) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "⚠️  Findings")
+		lines = append(lines, "===========")
+		for _, finding := range decision.Findings {
+			lines = append(lines, fmt.Sprintf("- [%s] %s", finding.Severity, finding.Message))
+		}
+	} else if len(decision.Warnings) > 0 {
 		lines = append(lines, "")
 		lines = append(lines, "⚠️  Warnings")
 		lines = append(lines, "===========")
@@ -194,10 +440,158 @@ func (f *Formatter) formatHuman(decision *policy.Decision) string {
 		lines = append(lines, "")
 		lines = append(lines, "📊 Metadata")
 		lines = append(lines, "===========")
-		for k, v := range decision.Metadata {
-			lines = append(lines, fmt.Sprintf("%s: %s", k, v))
+		for _, k := range sortedKeys(decision.Metadata) {
+			lines = append(lines, fmt.Sprintf("%s: %s", k, decision.Metadata[k]))
 		}
 	}
 
+	if decision.Trace != nil {
+		lines = append(lines, "")
+		lines = append(lines, f.formatTrace(decision.Trace)...)
+	}
+
 	return strings.Join(lines, "\n")
 }
+
+// formatPlan formats the stages of the decision's actions. Without an action
+// catalog the actions run one after another in the listed order.
+func (f *Formatter) formatPlan(decision *policy.Decision) string {
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf("🗺️  Plan for %s → %s", decision.BranchName, decision.Environment))
+	lines = append(lines, "==================")
+
+	plan := decision.Plan
+	if len(plan) == 0 && len(decision.Actions) > 0 {
+		lines = append(lines, "(no action catalog; actions run in the listed order)")
+		for _, action := range decision.Actions {
+			plan = append(plan, policy.PlanStage{Actions: []policy.PlannedAction{{Name: action}}})
+		}
+	}
+	if len(plan) == 0 {
+		lines = append(lines, "No actions")
+	}
+
+	for i, stage := range plan {
+		var actions []string
+		for _, action := range stage.Actions {
+			name := action.Name
+			if len(action.Params) > 0 {
+				var params []string
+				for _, k := range sortedKeys(action.Params) {
+					params = append(params, k+"="+action.Params[k])
+				}
+				name += " (" + strings.Join(params, ", ") + ")"
+			}
+			actions = append(actions, name)
+		}
+		lines = append(lines, fmt.Sprintf("Stage %d: %s", i+1, strings.Join(actions, ", ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// formatTrace formats a decision trace for human-readable output
+func (f *Formatter) formatTrace(trace *policy.Trace) []string {
+	var lines []string
+
+	lines = append(lines, "🔎 Decision Trace")
+	lines = append(lines, "=================")
+	lines = append(lines, "Mappings considered:")
+	for _, m := range trace.Mappings {
+		mark := "✗"
+		if m.Matched {
+			mark = "✓"
+		}
+		line := fmt.Sprintf("  %s [%d] %s → %s (priority %d)", mark, m.Index, m.Pattern, m.Environment, m.Priority)
+		if trace.Winner != nil && trace.Winner.Index == m.Index {
+			line += " ← selected"
+		}
+		lines = append(lines, line)
+	}
+	if trace.Winner == nil {
+		lines = append(lines, "  (no mapping matched)")
+	}
+
+	if a := trace.AllowedBranch; a != nil {
+		result := "not allowed"
+		if a.Allowed {
+			result = "allowed"
+			if a.MatchedBy != "" {
+				result += " by " + a.MatchedBy
+			}
+		}
+		patterns := strings.Join(a.Patterns, ", ")
+		if patterns == "" {
+			patterns = "(any)"
+		}
+		lines = append(lines, fmt.Sprintf("Allowed branches for %s: %s → %s", a.Environment, patterns, result))
+	}
+
+	if len(trace.Changes) > 0 {
+		lines = append(lines, "Changes:")
+		for _, c := range trace.Changes {
+			from := c.From
+			if from == "" {
+				from = "(unset)"
+			}
+			line := fmt.Sprintf("  - %s: %s → %s [%s]", c.Field, from, c.To, c.Source)
+			if c.Reason != "" {
+				line += " " + c.Reason
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if len(trace.Variables) > 0 {
+		lines = append(lines, "Variables:")
+		for _, v := range trace.Variables {
+			lines = append(lines, fmt.Sprintf("  %s=%s [%s]", v.Name, v.Value, v.Source))
+		}
+	}
+
+	return lines
+}
+
+// requiredApprovals returns the number of approvals required, or 0
+func requiredApprovals(approval *policy.Approval) int {
+	if approval == nil {
+		return 0
+	}
+	return approval.Required
+}
+
+// riskScore returns the risk score, or "" when risk is not scored
+func riskScore(risk *policy.Risk) string {
+	if risk == nil {
+		return ""
+	}
+	return strconv.Itoa(risk.Score)
+}
+
+// riskLevel returns the name of the highest risk threshold reached, or ""
+func riskLevel(risk *policy.Risk) string {
+	if risk == nil {
+		return ""
+	}
+	return risk.Level
+}
+
+// environmentNames returns the names of the decision's environments
+func environmentNames(decision *policy.Decision) []string {
+	var names []string
+	for _, env := range decision.Targets() {
+		names = append(names, env.Environment)
+	}
+	return names
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
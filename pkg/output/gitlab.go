@@ -0,0 +1,168 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+// defaultGitLabJobs is the file with the job templates the generated child
+// pipeline includes, unless BRANCHCI_GITLAB_JOBS names another one
+const defaultGitLabJobs = ".gitlab/branchci-jobs.yml"
+
+// gitLabVariableName matches the variable names GitLab accepts
+var gitLabVariableName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// formatGitLabDotenv formats a dotenv file for artifacts:reports:dotenv.
+// GitLab takes every value literally up to the end of the line and trims
+// it, so values are written unquoted and values it cannot carry are errors.
+func (f *Formatter) formatGitLabDotenv(decision *policy.Decision) (string, error) {
+	var lines []string
+	for _, v := range envVars(decision) {
+		if !gitLabVariableName.MatchString(v.name) {
+			return "", fmt.Errorf("variable %q: GitLab variable names may only contain letters, digits and underscores", v.name)
+		}
+		if strings.ContainsAny(v.value, "\r\n") {
+			return "", fmt.Errorf("variable %s: dotenv values cannot contain line breaks", v.name)
+		}
+		if strings.TrimSpace(v.value) != v.value {
+			return "", fmt.Errorf("variable %s: dotenv values cannot start or end with whitespace", v.name)
+		}
+		lines = append(lines, fmt.Sprintf("%s=%s", v.name, v.value))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatGitLabRules formats a child pipeline with a job for every action of
+// the decision, in the stages of its plan. Each job extends the hidden job of
+// the same name (".deploy" for deploy) from the included job templates; jobs
+// for other actions are left out. The deploy job is also left out when the
+// decision does not deploy and runs manually when it requires approval.
+func (f *Formatter) formatGitLabRules(decision *policy.Decision) (string, error) {
+	jobsFile := os.Getenv("BRANCHCI_GITLAB_JOBS")
+	if jobsFile == "" {
+		jobsFile = defaultGitLabJobs
+	}
+
+	// Variables are passed to every job of the child pipeline
+	variables := mapping()
+	for _, v := range envVars(decision) {
+		variables.Content = append(variables.Content, scalar(v.name), scalar(v.value))
+	}
+
+	var stages []string
+	var jobs []*yaml.Node
+	for i, stage := range jobStages(decision) {
+		stageName := fmt.Sprintf("stage-%d", i+1)
+		stages = append(stages, stageName)
+		for _, action := range stage {
+			job := mapping(scalar("extends"), scalar("."+action.Name), scalar("stage"), scalar(stageName))
+			if len(action.Needs) > 0 {
+				job.Content = append(job.Content, scalar("needs"), sequence(action.Needs))
+			}
+			if len(action.Params) > 0 {
+				params := mapping()
+				for _, k := range sortedKeys(action.Params) {
+					params.Content = append(params.Content, scalar(k), scalar(action.Params[k]))
+				}
+				job.Content = append(job.Content, scalar("variables"), params)
+			}
+			if action.Name == "deploy" {
+				job.Content = append(job.Content, scalar("environment"), mapping(scalar("name"), scalar(decision.Environment)))
+				if decision.RequiresApproval {
+					job.Content = append(job.Content, scalar("when"), scalar("manual"))
+				}
+			}
+			jobs = append(jobs, scalar(action.Name), job)
+		}
+	}
+
+	// A child pipeline needs at least one job
+	if len(jobs) == 0 {
+		stages = []string{"stage-1"}
+		jobs = append(jobs, scalar("branchci-no-actions"), mapping(
+			scalar("stage"), scalar("stage-1"),
+			scalar("script"), sequence([]string{"echo 'branch-aware-ci: no actions to run'"}),
+		))
+	}
+
+	doc := mapping(
+		scalar("include"), &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{mapping(scalar("local"), scalar(jobsFile))}},
+		scalar("stages"), sequence(stages),
+		scalar("variables"), variables,
+	)
+	doc.Content = append(doc.Content, jobs...)
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	header := fmt.Sprintf("# Generated by branch-aware-ci for %s → %s\n", decision.BranchName, decision.Environment)
+	return header + strings.TrimRight(string(data), "\n"), nil
+}
+
+// jobStages returns the actions of the decision to run as CI jobs, grouped
+// into the stages of its plan. Without an action catalog every action is a
+// stage of its own. The deploy action is left out when the decision does not
+// deploy; needs only name actions that are kept, and empty stages are dropped.
+func jobStages(decision *policy.Decision) [][]policy.PlannedAction {
+	plan := decision.Plan
+	if len(plan) == 0 {
+		for _, action := range decision.Actions {
+			plan = append(plan, policy.PlanStage{Actions: []policy.PlannedAction{{Name: action}}})
+		}
+	}
+
+	included := make(map[string]bool)
+	for _, stage := range plan {
+		for _, action := range stage.Actions {
+			included[action.Name] = action.Name != "deploy" || decision.ShouldDeploy
+		}
+	}
+
+	var stages [][]policy.PlannedAction
+	for _, stage := range plan {
+		var actions []policy.PlannedAction
+		for _, action := range stage.Actions {
+			if !included[action.Name] {
+				continue
+			}
+			var needs []string
+			for _, dep := range action.Needs {
+				if included[dep] {
+					needs = append(needs, dep)
+				}
+			}
+			action.Needs = needs
+			actions = append(actions, action)
+		}
+		if len(actions) > 0 {
+			stages = append(stages, actions)
+		}
+	}
+	return stages
+}
+
+// mapping returns a YAML mapping node with the given keys and values
+func mapping(content ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: content}
+}
+
+// scalar returns a YAML string node
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// sequence returns a YAML sequence node of strings
+func sequence(values []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range values {
+		node.Content = append(node.Content, scalar(v))
+	}
+	return node
+}
@@ -0,0 +1,132 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGitLabDotenv(t *testing.T) {
+	decision := &policy.Decision{
+		BranchName:  "main",
+		BranchType:  "main",
+		Environment: "production",
+		Actions:     []string{"deploy"},
+		Variables:   map[string]string{"URL": "https://example.com/?a=b", "GREETING": "hello 'world'"},
+	}
+
+	out, err := NewFormatter(FormatGitLabDotenv).Format(decision)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"BRANCH_NAME=main",
+		"BRANCH_TYPE=main",
+		"ENVIRONMENT=production",
+		"SHOULD_DEPLOY=false",
+		"REQUIRES_APPROVAL=false",
+		"ACTIONS=deploy",
+		"GREETING=hello 'world'",
+		"URL=https://example.com/?a=b",
+	}, "\n")
+	if out != want {
+		t.Errorf("Unexpected dotenv output:\n%s\nwant:\n%s", out, want)
+	}
+
+	for variables, wantErr := range map[string]string{
+		"my-var":  `variable "my-var": GitLab variable names may only contain letters, digits and underscores`,
+		"MESSAGE": "variable MESSAGE: dotenv values cannot contain line breaks",
+	} {
+		decision.Variables = map[string]string{variables: "line 1\nline 2"}
+		if _, err := NewFormatter(FormatGitLabDotenv).Format(decision); err == nil || err.Error() != wantErr {
+			t.Errorf("Expected error %q, got %v", wantErr, err)
+		}
+	}
+}
+
+func TestGitLabRules(t *testing.T) {
+	t.Setenv("BRANCHCI_GITLAB_JOBS", "ci/jobs.yml")
+
+	decision := &policy.Decision{
+		BranchName:       "main",
+		Environment:      "production",
+		ShouldDeploy:     true,
+		RequiresApproval: true,
+		Actions:          []string{"build", "test", "deploy"},
+		Variables:        map[string]string{},
+		Plan: []policy.PlanStage{
+			{Actions: []policy.PlannedAction{{Name: "build", Params: map[string]string{"TARGET": "linux"}}}},
+			{Actions: []policy.PlannedAction{{Name: "test", Needs: []string{"build"}}}},
+			{Actions: []policy.PlannedAction{{Name: "deploy", Needs: []string{"build", "test"}}}},
+		},
+	}
+
+	type job struct {
+		Extends     string            `yaml:"extends"`
+		Stage       string            `yaml:"stage"`
+		Needs       []string          `yaml:"needs"`
+		Variables   map[string]string `yaml:"variables"`
+		When        string            `yaml:"when"`
+		Environment struct {
+			Name string `yaml:"name"`
+		} `yaml:"environment"`
+	}
+	parse := func() (map[string]interface{}, map[string]job) {
+		t.Helper()
+		out, err := NewFormatter(FormatGitLabRules).Format(decision)
+		if err != nil {
+			t.Fatalf("Format failed: %v", err)
+		}
+		var doc map[string]interface{}
+		var nodes map[string]yaml.Node
+		if err := yaml.Unmarshal([]byte(out), &doc); err != nil {
+			t.Fatalf("Invalid YAML: %v\n%s", err, out)
+		}
+		yaml.Unmarshal([]byte(out), &nodes)
+		jobs := make(map[string]job)
+		for name, node := range nodes {
+			if name == "include" || name == "stages" || name == "variables" {
+				continue
+			}
+			var j job
+			if err := node.Decode(&j); err != nil {
+				t.Fatalf("Invalid job %s: %v", name, err)
+			}
+			jobs[name] = j
+		}
+		return doc, jobs
+	}
+
+	doc, jobs := parse()
+	if include := doc["include"].([]interface{})[0].(map[string]interface{}); include["local"] != "ci/jobs.yml" {
+		t.Errorf("Expected the job templates to be included, got %v", doc["include"])
+	}
+	if doc["variables"].(map[string]interface{})["ENVIRONMENT"] != "production" {
+		t.Errorf("Expected the decision variables, got %v", doc["variables"])
+	}
+	if j := jobs["build"]; j.Extends != ".build" || j.Stage != "stage-1" || j.Variables["TARGET"] != "linux" {
+		t.Errorf("Unexpected build job: %+v", j)
+	}
+	if j := jobs["deploy"]; j.Stage != "stage-3" || strings.Join(j.Needs, ",") != "build,test" || j.When != "manual" || j.Environment.Name != "production" {
+		t.Errorf("Unexpected deploy job: %+v", j)
+	}
+
+	// A decision that does not deploy leaves out the deploy job
+	decision.ShouldDeploy = false
+	doc, jobs = parse()
+	if _, exists := jobs["deploy"]; exists {
+		t.Errorf("Expected no deploy job, got %+v", jobs["deploy"])
+	}
+	if stages := doc["stages"].([]interface{}); len(stages) != 2 {
+		t.Errorf("Expected the empty stage to be left out, got %v", stages)
+	}
+
+	// A child pipeline without actions still has a job
+	decision.Actions, decision.Plan = []string{}, nil
+	_, jobs = parse()
+	if _, exists := jobs["branchci-no-actions"]; !exists || len(jobs) != 1 {
+		t.Errorf("Expected only the placeholder job, got %v", jobs)
+	}
+}
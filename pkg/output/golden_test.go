@@ -0,0 +1,175 @@
+package output
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+var update = flag.Bool("update", false, "Update the golden files in testdata")
+
+// specialDecision returns a decision with values that break naive key=value output
+func specialDecision() *policy.Decision {
+	return &policy.Decision{
+		BranchName:       "feature/quote's",
+		BranchType:       "feature",
+		Environment:      "development",
+		ShouldDeploy:     true,
+		RequiresApproval: false,
+		Actions:          []string{"build", "test"},
+		Variables: map[string]string{
+			"URL":       "https://example.com/?a=1&b=2",
+			"GREETING":  `say "hi" to $USER`,
+			"NOTES":     "line one\nline two\nBRANCHCI_EOF",
+			"BACKTICKS": "`id` $(id)",
+			"SPACES":    "  padded  ",
+			"EMPTY":     "",
+		},
+	}
+}
+
+// checkGolden compares got to testdata/name, rewriting the file with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s does not match the golden file:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for _, format := range []Format{FormatEnv, FormatShell} {
+		out, err := NewFormatter(format).Format(specialDecision())
+		if err != nil {
+			t.Fatalf("%s: Format failed: %v", format, err)
+		}
+		checkGolden(t, string(format)+".golden", out+"\n")
+	}
+
+	for _, tc := range []struct {
+		format Format
+		env    string
+	}{
+		{FormatGitHubEnv, "GITHUB_ENV"},
+		{FormatGitHubOutput, "GITHUB_OUTPUT"},
+	} {
+		path := filepath.Join(t.TempDir(), tc.env)
+		t.Setenv(tc.env, path)
+		if _, err := NewFormatter(tc.format).Format(specialDecision()); err != nil {
+			t.Fatalf("%s: Format failed: %v", tc.format, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, string(tc.format)+".golden", string(data))
+	}
+}
+
+func TestShellRoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	decision := specialDecision()
+	for _, format := range []Format{FormatEnv, FormatShell} {
+		out, err := NewFormatter(format).Format(decision)
+		if err != nil {
+			t.Fatalf("%s: Format failed: %v", format, err)
+		}
+		for name, want := range decision.Variables {
+			script := out + "\nprintf '%s' \"$" + name + "\""
+			got, err := exec.Command(sh, "-c", script).Output()
+			if err != nil {
+				t.Fatalf("%s: sourcing the output failed: %v", format, err)
+			}
+			if string(got) != want {
+				t.Errorf("%s: %s = %q, want %q", format, name, got, want)
+			}
+		}
+	}
+}
+
+func TestInvalidVariableNames(t *testing.T) {
+	t.Setenv("GITHUB_ENV", filepath.Join(t.TempDir(), "GITHUB_ENV"))
+	for _, name := range []string{"1ST", "MY-VAR", "A=B", "LINE\nBREAK"} {
+		decision := specialDecision()
+		decision.Variables[name] = "x"
+		for _, format := range []Format{FormatEnv, FormatShell, FormatGitHubEnv} {
+			if _, err := NewFormatter(format).Format(decision); err == nil {
+				t.Errorf("%s: expected an error for variable name %q", format, name)
+			}
+		}
+	}
+}
+
+func TestGitHubFileEntry(t *testing.T) {
+	for _, tc := range []struct{ value, want string }{
+		{"a=b", "K=a=b"},
+		{"one\ntwo", "K<<BRANCHCI_EOF\none\ntwo\nBRANCHCI_EOF"},
+		{"BRANCHCI_EOF\nBRANCHCI_EOF_1\r\nx", "K<<BRANCHCI_EOF_2\nBRANCHCI_EOF\nBRANCHCI_EOF_1\r\nx\nBRANCHCI_EOF_2"},
+	} {
+		if got := githubFileEntry("K", tc.value); got != tc.want {
+			t.Errorf("githubFileEntry(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+	if got := githubFileEntry("K", "x BRANCHCI_EOF"); strings.Contains(got, "<<") {
+		t.Errorf("Expected a single-line value to be written as K=value, got %q", got)
+	}
+}
+
+func TestGitHubOutputVariables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GITHUB_OUTPUT")
+	t.Setenv("GITHUB_OUTPUT", path)
+
+	decision := specialDecision()
+	decision.Warnings = []string{`Branch "feature/x" is stale`}
+	decision.Metadata = map[string]string{"ticket": "JIRA-123"}
+	decision.Variables = map[string]string{"ENV": "development", "LOG_LEVEL": "debug"}
+
+	t.Setenv("BRANCHCI_OUTPUT_PREFIX", "")
+	if _, err := NewFormatter(FormatGitHubOutput).Format(decision); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\nwarnings=[\"Branch \\\"feature/x\\\" is stale\"]\n",
+		"\nticket=JIRA-123\n",
+		"\nsuffix=\n",
+		"\ndecision_json={\"branch_name\":\"feature/quote's\"",
+		"\nENV=development\n",
+		"\nLOG_LEVEL=debug\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in:\n%s", want, data)
+		}
+	}
+
+	// Without a prefix a variable may collide with an output of the decision
+	decision.Variables["ENVIRONMENT"] = "x"
+	if _, err := NewFormatter(FormatGitHubOutput).Format(decision); err == nil {
+		t.Error("Expected an error for a variable named like an output")
+	}
+	t.Setenv("BRANCHCI_OUTPUT_PREFIX", "bad prefix ")
+	if _, err := NewFormatter(FormatGitHubOutput).Format(decision); err == nil {
+		t.Error("Expected an error for an invalid output name")
+	}
+}
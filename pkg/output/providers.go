@@ -0,0 +1,237 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+// defaultBuildkiteActions is the directory with a script per action that the
+// generated Buildkite steps run, unless BRANCHCI_BUILDKITE_ACTIONS names another one
+const defaultBuildkiteActions = ".buildkite/actions"
+
+// Provider is a CI provider and the format that passes decisions to it
+type Provider struct {
+	Name   string
+	Format Format
+	detect func() bool
+}
+
+// Providers are the CI providers the auto format detects, in detection order
+var Providers = []Provider{
+	{Name: "GitHub Actions", Format: FormatGitHubOutput, detect: envEquals("GITHUB_ACTIONS", "true")},
+	{Name: "GitLab CI", Format: FormatGitLabDotenv, detect: envEquals("GITLAB_CI", "true")},
+	{Name: "Azure Pipelines", Format: FormatAzure, detect: envEquals("TF_BUILD", "True")},
+	{Name: "Buildkite", Format: FormatBuildkite, detect: envEquals("BUILDKITE", "true")},
+	{Name: "CircleCI", Format: FormatCircleCI, detect: envEquals("CIRCLECI", "true")},
+	{Name: "Jenkins", Format: FormatJenkins, detect: envSet("JENKINS_URL")},
+}
+
+// DetectProvider returns the CI provider the process runs under, or nil
+func DetectProvider() *Provider {
+	for i := range Providers {
+		if Providers[i].detect() {
+			return &Providers[i]
+		}
+	}
+	return nil
+}
+
+// envEquals reports whether an environment variable has the given value
+func envEquals(name, value string) func() bool {
+	return func() bool {
+		return os.Getenv(name) == value
+	}
+}
+
+// envSet reports whether an environment variable is set
+func envSet(name string) func() bool {
+	return func() bool {
+		return os.Getenv(name) != ""
+	}
+}
+
+// shellVariableName matches the variable names a POSIX shell accepts
+var shellVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// azureVariableName matches the variable names Azure Pipelines accepts
+var azureVariableName = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
+// formatAuto formats for the detected CI provider, or for humans outside CI
+func (f *Formatter) formatAuto(decision *policy.Decision) (string, error) {
+	format := FormatHuman
+	if provider := DetectProvider(); provider != nil {
+		format = provider.Format
+	}
+	return NewFormatter(format).Format(decision)
+}
+
+// formatAzure formats Azure Pipelines logging commands. Each variable is set
+// for the following steps of the job and as an output variable for later
+// jobs and stages.
+func (f *Formatter) formatAzure(decision *policy.Decision) (string, error) {
+	escape := strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A")
+
+	var lines []string
+	for _, v := range envVars(decision) {
+		if !azureVariableName.MatchString(v.name) {
+			return "", fmt.Errorf("variable %q: Azure Pipelines variable names may only contain letters, digits, '.' and '_'", v.name)
+		}
+		value := escape.Replace(v.value)
+		lines = append(lines, fmt.Sprintf("##vso[task.setvariable variable=%s]%s", v.name, value))
+		lines = append(lines, fmt.Sprintf("##vso[task.setvariable variable=%s;isOutput=true]%s", v.name, value))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatJenkins formats a Java properties file for the readProperties step
+func (f *Formatter) formatJenkins(decision *policy.Decision) string {
+	lines := []string{"# Generated by branch-aware-ci"}
+	for _, v := range envVars(decision) {
+		lines = append(lines, escapeProperty(v.name, true)+"="+escapeProperty(v.value, false))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeProperty escapes a key or value of a Java properties file
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		case (r == '=' || r == ':') && key:
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case (r == '#' || r == '!') && i == 0:
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// formatBuildkite formats a shell script that stores every variable as
+// Buildkite build meta-data. Empty values are skipped because meta-data
+// cannot be empty; read them with meta-data get --default.
+func (f *Formatter) formatBuildkite(decision *policy.Decision) string {
+	lines := []string{"#!/bin/sh", "set -e"}
+	for _, v := range envVars(decision) {
+		if v.value == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("buildkite-agent meta-data set %s %s", shellQuote(v.name), shellQuote(v.value)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatBuildkitePipeline formats steps for buildkite-agent pipeline upload:
+// a step for every action of the decision running its script from
+// .buildkite/actions (or the directory named by BRANCHCI_BUILDKITE_ACTIONS),
+// with a wait between the stages of the plan and a block step before
+// deploying when the decision requires approval
+func (f *Formatter) formatBuildkitePipeline(decision *policy.Decision) (string, error) {
+	dir := os.Getenv("BRANCHCI_BUILDKITE_ACTIONS")
+	if dir == "" {
+		dir = defaultBuildkiteActions
+	}
+
+	env := mapping()
+	for _, v := range envVars(decision) {
+		env.Content = append(env.Content, scalar(v.name), scalar(v.value))
+	}
+
+	steps := &yaml.Node{Kind: yaml.SequenceNode}
+	for i, stage := range jobStages(decision) {
+		if i > 0 {
+			steps.Content = append(steps.Content, scalar("wait"))
+		}
+		for _, action := range stage {
+			if action.Name == "deploy" && decision.RequiresApproval {
+				steps.Content = append(steps.Content, mapping(
+					scalar("block"), scalar(fmt.Sprintf("Deploy to %s", decision.Environment)),
+					scalar("key"), scalar("approve-deploy"),
+				))
+			}
+			step := mapping(
+				scalar("label"), scalar(action.Name),
+				scalar("key"), scalar(action.Name),
+				scalar("command"), scalar(fmt.Sprintf("%s/%s.sh", strings.TrimSuffix(dir, "/"), action.Name)),
+			)
+			if len(action.Params) > 0 {
+				params := mapping()
+				for _, k := range sortedKeys(action.Params) {
+					params.Content = append(params.Content, scalar(k), scalar(action.Params[k]))
+				}
+				step.Content = append(step.Content, scalar("env"), params)
+			}
+			steps.Content = append(steps.Content, step)
+		}
+	}
+
+	// An upload needs at least one step
+	if len(steps.Content) == 0 {
+		steps.Content = append(steps.Content, mapping(
+			scalar("label"), scalar("branch-aware-ci"),
+			scalar("command"), scalar("echo 'branch-aware-ci: no actions to run'"),
+		))
+	}
+
+	data, err := yaml.Marshal(mapping(scalar("env"), env, scalar("steps"), steps))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	header := fmt.Sprintf("# Generated by branch-aware-ci for %s → %s\n", decision.BranchName, decision.Environment)
+	return header + strings.TrimRight(string(data), "\n"), nil
+}
+
+// formatCircleCI appends export statements to the file named by BASH_ENV,
+// which CircleCI sources before every following step
+func (f *Formatter) formatCircleCI(decision *policy.Decision) (string, error) {
+	envFile := os.Getenv("BASH_ENV")
+	if envFile == "" {
+		return "", fmt.Errorf("BASH_ENV not set")
+	}
+
+	var lines []string
+	for _, v := range envVars(decision) {
+		if err := validateShellName(v.name); err != nil {
+			return "", err
+		}
+		lines = append(lines, fmt.Sprintf("export %s=%s", v.name, shellQuote(v.value)))
+	}
+
+	file, err := os.OpenFile(envFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open BASH_ENV file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return "", fmt.Errorf("failed to write to BASH_ENV: %w", err)
+	}
+
+	return "Environment variables written to $BASH_ENV", nil
+}
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
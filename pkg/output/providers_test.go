@@ -0,0 +1,155 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+// providerDecision returns a decision with values that need escaping
+func providerDecision() *policy.Decision {
+	return &policy.Decision{
+		BranchName:   "main",
+		BranchType:   "main",
+		Environment:  "production",
+		ShouldDeploy: true,
+		Actions:      []string{"deploy"},
+		Variables:    map[string]string{"NOTES": "50% done\nit's: #1", "EMPTY": ""},
+	}
+}
+
+func TestAzure(t *testing.T) {
+	out, err := NewFormatter(FormatAzure).Format(providerDecision())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	for _, want := range []string{
+		"##vso[task.setvariable variable=ENVIRONMENT]production\n",
+		"##vso[task.setvariable variable=ENVIRONMENT;isOutput=true]production\n",
+		"##vso[task.setvariable variable=NOTES]50%AZP25 done%0Ait's: #1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+
+	decision := providerDecision()
+	decision.Variables["BAD;NAME"] = "x"
+	if _, err := NewFormatter(FormatAzure).Format(decision); err == nil {
+		t.Error("Expected an error for an invalid variable name")
+	}
+}
+
+func TestJenkins(t *testing.T) {
+	out, err := NewFormatter(FormatJenkins).Format(providerDecision())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	for _, want := range []string{"\nENVIRONMENT=production\n", "\nEMPTY=\n", `NOTES=50% done\nit's: #1`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+
+	for _, tc := range []struct{ in, key, value string }{
+		{"a=b:c d", `a\=b\:c\ d`, `a=b:c d`},
+		{" #x\\y", `\ #x\\y`, `\ #x\\y`},
+		{"#!", `\#!`, `\#!`},
+	} {
+		if got := escapeProperty(tc.in, true); got != tc.key {
+			t.Errorf("escapeProperty(%q, key) = %q, want %q", tc.in, got, tc.key)
+		}
+		if got := escapeProperty(tc.in, false); got != tc.value {
+			t.Errorf("escapeProperty(%q, value) = %q, want %q", tc.in, got, tc.value)
+		}
+	}
+}
+
+func TestBuildkite(t *testing.T) {
+	out, err := NewFormatter(FormatBuildkite).Format(providerDecision())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(out, "buildkite-agent meta-data set 'NOTES' '50% done\nit'\\''s: #1'") {
+		t.Errorf("Expected the value to be shell-quoted, got:\n%s", out)
+	}
+	if strings.Contains(out, "EMPTY") {
+		t.Errorf("Expected empty values to be skipped, got:\n%s", out)
+	}
+
+	t.Setenv("BRANCHCI_BUILDKITE_ACTIONS", "ci/steps/")
+	decision := providerDecision()
+	decision.RequiresApproval = true
+	decision.Actions = []string{"test", "deploy"}
+	out, err = NewFormatter(FormatBuildkitePipeline).Format(decision)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	want := `steps:
+    - label: test
+      key: test
+      command: ci/steps/test.sh
+    - wait
+    - block: Deploy to production
+      key: approve-deploy
+    - label: deploy
+      key: deploy
+      command: ci/steps/deploy.sh`
+	if !strings.HasSuffix(out, want) {
+		t.Errorf("Unexpected pipeline:\n%s", out)
+	}
+}
+
+func TestCircleCI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bash_env")
+	t.Setenv("BASH_ENV", path)
+
+	if _, err := NewFormatter(FormatCircleCI).Format(providerDecision()); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"export ENVIRONMENT='production'\n", "export EMPTY=''\n", "export NOTES='50% done\nit'\\''s: #1'\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in:\n%s", want, data)
+		}
+	}
+
+	decision := providerDecision()
+	decision.Variables["1ST"] = "x"
+	if _, err := NewFormatter(FormatCircleCI).Format(decision); err == nil {
+		t.Error("Expected an error for an invalid variable name")
+	}
+}
+
+func TestDetectProvider(t *testing.T) {
+	for _, name := range []string{"GITHUB_ACTIONS", "GITLAB_CI", "TF_BUILD", "BUILDKITE", "CIRCLECI", "JENKINS_URL"} {
+		t.Setenv(name, "")
+	}
+	if provider := DetectProvider(); provider != nil {
+		t.Fatalf("Expected no provider, got %s", provider.Name)
+	}
+	out, err := NewFormatter(FormatAuto).Format(providerDecision())
+	if err != nil || !strings.HasPrefix(out, "🌿 Branch Analysis") {
+		t.Errorf("Expected human output outside CI, got %v:\n%s", err, out)
+	}
+
+	t.Setenv("TF_BUILD", "True")
+	if provider := DetectProvider(); provider == nil || provider.Format != FormatAzure {
+		t.Fatalf("Expected Azure Pipelines, got %+v", provider)
+	}
+	out, err = NewFormatter(FormatAuto).Format(providerDecision())
+	if err != nil || !strings.HasPrefix(out, "##vso[task.setvariable") {
+		t.Errorf("Expected Azure logging commands, got %v:\n%s", err, out)
+	}
+
+	t.Setenv("JENKINS_URL", "https://jenkins.example.com")
+	if provider := DetectProvider(); provider.Name != "Azure Pipelines" {
+		t.Errorf("Expected detection in order, got %s", provider.Name)
+	}
+}
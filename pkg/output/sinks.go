@@ -0,0 +1,121 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+// Formats are the supported output formats
+var Formats = []Format{
+	FormatJSON, FormatYAML, FormatEnv, FormatShell, FormatGitHubEnv, FormatGitHubOutput, FormatGitHubSummary,
+	FormatHuman, FormatPlan, FormatGitLabDotenv, FormatGitLabRules, FormatAzure, FormatJenkins,
+	FormatBuildkite, FormatBuildkitePipeline, FormatCircleCI, FormatAuto,
+}
+
+// Sink is a format to write a decision in and where to write it
+type Sink struct {
+	Format Format
+	Path   string // Empty for standard output
+}
+
+// ParseSinks returns the sinks for the given -format and -out values. Each
+// value may list several entries separated by commas. An -out entry of the
+// form format=path writes that format to a file, adding the format if no
+// -format value names it; every other format is written to standard output.
+// Formats named more than once are written once.
+func ParseSinks(formats, outs []string) ([]Sink, error) {
+	var sinks []Sink
+	index := make(map[Format]int)
+	add := func(format Format) (*Sink, error) {
+		if !validFormat(format) {
+			return nil, fmt.Errorf("unsupported format: %s", format)
+		}
+		if i, exists := index[format]; exists {
+			return &sinks[i], nil
+		}
+		index[format] = len(sinks)
+		sinks = append(sinks, Sink{Format: format})
+		return &sinks[len(sinks)-1], nil
+	}
+
+	for _, format := range splitList(formats) {
+		if _, err := add(Format(format)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, out := range splitList(outs) {
+		format, path, ok := strings.Cut(out, "=")
+		format, path = strings.TrimSpace(format), strings.TrimSpace(path)
+		if !ok || format == "" || path == "" {
+			return nil, fmt.Errorf("invalid output %q (expected format=path)", out)
+		}
+		sink, err := add(Format(format))
+		if err != nil {
+			return nil, err
+		}
+		if sink.Path != "" {
+			return nil, fmt.Errorf("format %s is written to both %s and %s", format, sink.Path, path)
+		}
+		sink.Path = path
+	}
+
+	return sinks, nil
+}
+
+// WriteSinks formats the decision once for every sink, writing it to the
+// sink's file or to stdout. With a github-summary sink, the commands masking
+// secret variables are printed first, so no other output reveals them.
+func WriteSinks(decision *policy.Decision, sinks []Sink, stdout io.Writer) error {
+	for _, sink := range sinks {
+		if sink.Format == FormatGitHubSummary {
+			for _, line := range maskCommands(decision) {
+				fmt.Fprintln(stdout, line)
+			}
+			break
+		}
+	}
+
+	for _, sink := range sinks {
+		result, err := NewFormatter(sink.Format).Format(decision)
+		if err != nil {
+			return fmt.Errorf("failed to format %s output: %w", sink.Format, err)
+		}
+
+		if sink.Path == "" {
+			fmt.Fprintln(stdout, result)
+			continue
+		}
+		if err := os.WriteFile(sink.Path, []byte(result+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write %s output: %w", sink.Format, err)
+		}
+	}
+	return nil
+}
+
+// validFormat reports whether format is a supported output format
+func validFormat(format Format) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits comma-separated values into their non-empty entries
+func splitList(values []string) []string {
+	var entries []string
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSinks(t *testing.T) {
+	sinks, err := ParseSinks(
+		[]string{"github-env, github-output", "json", "github-env"},
+		[]string{"json=decision.json", "yaml=decision.yml,plan=plan.txt"},
+	)
+	if err != nil {
+		t.Fatalf("ParseSinks failed: %v", err)
+	}
+	want := []Sink{
+		{Format: FormatGitHubEnv},
+		{Format: FormatGitHubOutput},
+		{Format: FormatJSON, Path: "decision.json"},
+		{Format: FormatYAML, Path: "decision.yml"},
+		{Format: FormatPlan, Path: "plan.txt"},
+	}
+	if !reflect.DeepEqual(sinks, want) {
+		t.Errorf("ParseSinks = %+v, want %+v", sinks, want)
+	}
+
+	// Empty values are ignored, e.g. an unset action input
+	if sinks, err := ParseSinks([]string{"json"}, []string{""}); err != nil || len(sinks) != 1 {
+		t.Errorf("Expected empty -out values to be ignored, got %+v, %v", sinks, err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		formats, outs []string
+	}{
+		{"unknown format", []string{"xml"}, nil},
+		{"unknown out format", nil, []string{"xml=out.xml"}},
+		{"missing path", nil, []string{"json"}},
+		{"empty path", nil, []string{"json="}},
+		{"two paths", nil, []string{"json=a.json", "json=b.json"}},
+	} {
+		if _, err := ParseSinks(tc.formats, tc.outs); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestWriteSinks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "decision.json")
+
+	var stdout bytes.Buffer
+	sinks := []Sink{{Format: FormatEnv}, {Format: FormatJSON, Path: path}}
+	if err := WriteSinks(providerDecision(), sinks, &stdout); err != nil {
+		t.Fatalf("WriteSinks failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "ENVIRONMENT=production\n") || strings.Contains(stdout.String(), `"environment"`) {
+		t.Errorf("Expected only the env format on stdout, got:\n%s", stdout.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"environment": "production"`) {
+		t.Errorf("Expected the JSON decision in %s, got:\n%s", path, data)
+	}
+
+	t.Setenv("GITHUB_OUTPUT", "")
+	err = WriteSinks(providerDecision(), []Sink{{Format: FormatGitHubOutput}}, &stdout)
+	if err == nil || !strings.Contains(err.Error(), "github-output") {
+		t.Errorf("Expected an error naming the failing format, got %v", err)
+	}
+}
+
+func TestWriteSinksMasksFirst(t *testing.T) {
+	t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(t.TempDir(), "summary.md"))
+	decision := providerDecision()
+	decision.Variables["API_TOKEN"] = "s3cr3t"
+
+	// The summary comes last, but its masks precede every output revealing the secret
+	var stdout bytes.Buffer
+	sinks := []Sink{{Format: FormatHuman}, {Format: FormatJSON}, {Format: FormatEnv}, {Format: FormatGitHubSummary}}
+	if err := WriteSinks(decision, sinks, &stdout); err != nil {
+		t.Fatalf("WriteSinks failed: %v", err)
+	}
+	out := stdout.String()
+	if !strings.HasPrefix(out, "::add-mask::s3cr3t\n") {
+		t.Errorf("Expected the mask command first, got:\n%s", out)
+	}
+	if strings.Count(out, "::add-mask::") != 1 {
+		t.Errorf("Expected one mask command, got:\n%s", out)
+	}
+
+	// Without a summary nothing is masked
+	stdout.Reset()
+	if err := WriteSinks(decision, sinks[:3], &stdout); err != nil {
+		t.Fatalf("WriteSinks failed: %v", err)
+	}
+	if strings.Contains(stdout.String(), "::add-mask::") {
+		t.Errorf("Expected no mask commands without a github-summary sink, got:\n%s", stdout.String())
+	}
+}
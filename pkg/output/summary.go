@@ -0,0 +1,188 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+// secretVariableName matches variable names whose values are masked in the
+// step summary and the log
+var secretVariableName = regexp.MustCompile(`(?i)(SECRET|TOKEN|PASSWORD|PASSWD|CREDENTIAL|PRIVATE|API_?KEY|ACCESS_?KEY|(^|_)KEY$)`)
+
+// formatGitHubSummary appends a Markdown summary of the decision to the job
+// summary file named by GITHUB_STEP_SUMMARY and returns workflow commands
+// that annotate the run with the decision's findings. The commands masking
+// secret variables come from maskCommands, which WriteSinks prints before
+// any output.
+func (f *Formatter) formatGitHubSummary(decision *policy.Decision) (string, error) {
+	summaryFile := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryFile == "" {
+		return "", fmt.Errorf("GITHUB_STEP_SUMMARY not set")
+	}
+
+	file, err := os.OpenFile(summaryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open GITHUB_STEP_SUMMARY file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(formatSummaryMarkdown(decision) + "\n"); err != nil {
+		return "", fmt.Errorf("failed to write to GITHUB_STEP_SUMMARY: %w", err)
+	}
+
+	lines := append(annotations(decision), "Step summary written to $GITHUB_STEP_SUMMARY")
+	return strings.Join(lines, "\n"), nil
+}
+
+// maskCommands returns workflow commands that mask the values of secret
+// variables in the log
+func maskCommands(decision *policy.Decision) []string {
+	var lines []string
+	for _, k := range sortedKeys(decision.Variables) {
+		if secretVariableName.MatchString(k) && decision.Variables[k] != "" {
+			lines = append(lines, "::add-mask::"+escapeCommandData(decision.Variables[k]))
+		}
+	}
+	return lines
+}
+
+// formatSummaryMarkdown formats the decision, its warnings and its variables
+// as Markdown tables
+func formatSummaryMarkdown(decision *policy.Decision) string {
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf("### 🌿 Branch-Aware CI: `%s` → `%s`", decision.BranchName, decision.Environment))
+	lines = append(lines, "")
+	lines = append(lines, "| Decision | |")
+	lines = append(lines, "|----------|---|")
+	lines = append(lines, fmt.Sprintf("| Branch | %s (%s) |", code(decision.BranchName), decision.BranchType))
+	environment := code(decision.Environment)
+	if decision.Ephemeral {
+		environment += " (ephemeral)"
+	}
+	lines = append(lines, fmt.Sprintf("| Environment | %s |", environment))
+	if decision.Teardown {
+		lines = append(lines, "| Teardown | 🧹 Yes |")
+	}
+	lines = append(lines, fmt.Sprintf("| Should deploy | %s |", yesNo(decision.ShouldDeploy, "✅ Yes", "❌ No")))
+	approval := yesNo(decision.RequiresApproval, "⚠️ Yes", "No")
+	if decision.RequiresApproval && decision.Approval != nil {
+		approval += " (" + markdownCell(decision.Approval.String()) + ")"
+	}
+	lines = append(lines, fmt.Sprintf("| Requires approval | %s |", approval))
+	if decision.Risk != nil {
+		lines = append(lines, fmt.Sprintf("| Risk score | %s |", markdownCell(decision.Risk.String())))
+	}
+	if len(decision.Actions) > 0 {
+		actions := make([]string, len(decision.Actions))
+		for i, action := range decision.Actions {
+			actions[i] = code(action)
+		}
+		lines = append(lines, fmt.Sprintf("| Actions | %s |", strings.Join(actions, ", ")))
+	}
+
+	if len(decision.Environments) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "| Environment | Deploy | Approval |")
+		lines = append(lines, "|-------------|--------|----------|")
+		for _, env := range decision.Environments {
+			approval := yesNo(env.RequiresApproval, "Yes", "No")
+			if env.Approval != nil {
+				approval = markdownCell(env.Approval.String())
+			}
+			lines = append(lines, fmt.Sprintf("| %s | %s | %s |", code(env.Environment), yesNo(env.ShouldDeploy, "✅", "❌"), approval))
+		}
+	}
+
+	if len(decision.Findings) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "| Severity | Finding |")
+		lines = append(lines, "|----------|---------|")
+		for _, finding := range decision.Findings {
+			lines = append(lines, fmt.Sprintf("| %s | %s %s |", finding.Severity, code(finding.Code), markdownCell(finding.Message)))
+		}
+	} else if len(decision.Warnings) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "| Warning |")
+		lines = append(lines, "|---------|")
+		for _, warning := range decision.Warnings {
+			lines = append(lines, fmt.Sprintf("| %s |", markdownCell(warning)))
+		}
+	}
+
+	if len(decision.Variables) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "| Variable | Value |")
+		lines = append(lines, "|----------|-------|")
+		for _, k := range sortedKeys(decision.Variables) {
+			value := code(decision.Variables[k])
+			if secretVariableName.MatchString(k) {
+				value = "`***`"
+			}
+			lines = append(lines, fmt.Sprintf("| %s | %s |", code(k), value))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// annotations returns a workflow command for every warn or error finding of
+// the decision, so they show as annotations on the run
+func annotations(decision *policy.Decision) []string {
+	var lines []string
+	if len(decision.Findings) == 0 {
+		for _, warning := range decision.Warnings {
+			lines = append(lines, "::warning::"+escapeCommandData(warning))
+		}
+		return lines
+	}
+
+	for _, finding := range decision.Findings {
+		var command string
+		switch finding.Severity {
+		case policy.SeverityError:
+			command = "error"
+		case policy.SeverityWarn:
+			command = "warning"
+		default:
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("::%s title=%s::%s", command, escapeCommandProperty(finding.Code), escapeCommandData(finding.Message)))
+	}
+	return lines
+}
+
+// escapeCommandData escapes the message of a workflow command
+func escapeCommandData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeCommandProperty escapes a property value of a workflow command
+func escapeCommandProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// code renders a value as inline code in a table cell
+func code(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "`" + markdownCell(value) + "`"
+}
+
+// markdownCell escapes a value for a Markdown table cell
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", `\|`, "\r", "", "\n", "<br>").Replace(value)
+}
+
+// yesNo returns yes when b is true, and no otherwise
+func yesNo(b bool, yes, no string) string {
+	if b {
+		return yes
+	}
+	return no
+}
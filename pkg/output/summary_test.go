@@ -0,0 +1,82 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+func TestGitHubSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", path)
+
+	decision := &policy.Decision{
+		BranchName:       "main",
+		BranchType:       "main",
+		Environment:      "production",
+		ShouldDeploy:     false,
+		RequiresApproval: true,
+		Approval:         &policy.Approval{Required: 2, Teams: []string{"@org/sre"}},
+		Actions:          []string{"deploy"},
+		Variables:        map[string]string{"ENV": "production", "DEPLOY_TOKEN": "s3cr3t", "SIGNING_KEY": "k"},
+		Findings: []policy.Finding{
+			{Severity: policy.SeverityInfo, Code: "risk_threshold", Message: "Risk score 40 reached the high threshold (40)"},
+			{Severity: policy.SeverityWarn, Code: "rule_warning", Message: "Large change: 100% of files"},
+			{Severity: policy.SeverityError, Code: "freeze_window", Message: "Production is frozen\nuntil Monday"},
+		},
+	}
+
+	out, err := NewFormatter(FormatGitHubSummary).Format(decision)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"::warning title=rule_warning::Large change: 100%25 of files",
+		"::error title=freeze_window::Production is frozen%0Auntil Monday",
+		"Step summary written to $GITHUB_STEP_SUMMARY",
+	}, "\n")
+	if out != want {
+		t.Errorf("Unexpected workflow commands:\n%s\nwant:\n%s", out, want)
+	}
+
+	if masks := maskCommands(decision); !reflect.DeepEqual(masks, []string{"::add-mask::s3cr3t", "::add-mask::k"}) {
+		t.Errorf("Unexpected mask commands: %v", masks)
+	}
+
+	// A second run appends to the summary
+	if _, err := NewFormatter(FormatGitHubSummary).Format(decision); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := string(data)
+	if strings.Count(summary, "### 🌿 Branch-Aware CI: `main` → `production`") != 2 {
+		t.Errorf("Expected the summary to be appended twice, got:\n%s", summary)
+	}
+	for _, want := range []string{
+		"| Should deploy | ❌ No |",
+		"| Requires approval | ⚠️ Yes (2 approvals from @org/sre; self-approval not allowed) |",
+		"| error | `freeze_window` Production is frozen<br>until Monday |",
+		"| `ENV` | `production` |",
+		"| `DEPLOY_TOKEN` | `***` |",
+		"| `SIGNING_KEY` | `***` |",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected %q in summary:\n%s", want, summary)
+		}
+	}
+	if strings.Contains(summary, "s3cr3t") {
+		t.Errorf("Expected secret values to be masked:\n%s", summary)
+	}
+
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+	if _, err := NewFormatter(FormatGitHubSummary).Format(decision); err == nil {
+		t.Error("Expected an error without GITHUB_STEP_SUMMARY")
+	}
+}
@@ -0,0 +1,175 @@
+package pattern
+
+import (
+	"regexp/syntax"
+	"unicode"
+)
+
+// Overlaps reports whether at least one branch name matches both patterns.
+//
+// Positive patterns are compared exactly by walking both compiled regular
+// expressions in lockstep. Negated patterns are treated conservatively: they
+// overlap with everything except exact names they reject.
+func Overlaps(a, b *Pattern) bool {
+	if a.negate || b.negate {
+		for _, pair := range [][2]*Pattern{{a, b}, {b, a}} {
+			negated, other := pair[0], pair[1]
+			if negated.negate && !other.negate {
+				if name, exact := other.re.LiteralPrefix(); exact {
+					return negated.Match(name)
+				}
+			}
+		}
+		return true
+	}
+
+	progA, errA := compileProg(a.re.String())
+	progB, errB := compileProg(b.re.String())
+	if errA != nil || errB != nil {
+		return true
+	}
+
+	return intersects(progA, progB)
+}
+
+// compileProg compiles a regular expression into its instruction program
+func compileProg(expr string) (*syntax.Prog, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	return syntax.Compile(re.Simplify())
+}
+
+// instPair is a state of the product of two programs
+type instPair struct {
+	a, b uint32
+}
+
+// intersects reports whether both programs accept a common string
+func intersects(progA, progB *syntax.Prog) bool {
+	runesA, matchA := closure(progA, uint32(progA.Start), true)
+	runesB, matchB := closure(progB, uint32(progB.Start), true)
+	if matchA && matchB {
+		return true
+	}
+
+	seen := make(map[instPair]bool)
+	var queue []instPair
+	push := func(as, bs []uint32) {
+		for _, a := range as {
+			for _, b := range bs {
+				p := instPair{a, b}
+				if !seen[p] {
+					seen[p] = true
+					queue = append(queue, p)
+				}
+			}
+		}
+	}
+	push(runesA, runesB)
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		instA, instB := &progA.Inst[p.a], &progB.Inst[p.b]
+		if !rangesIntersect(runeRanges(instA), runeRanges(instB)) {
+			continue
+		}
+
+		nextA, matchA := closure(progA, instA.Out, false)
+		nextB, matchB := closure(progB, instB.Out, false)
+		if matchA && matchB {
+			return true
+		}
+		push(nextA, nextB)
+	}
+
+	return false
+}
+
+// closure follows empty transitions from pc. It returns the reachable
+// rune-consuming instructions and whether a match is reachable at end of input.
+func closure(prog *syntax.Prog, pc uint32, atStart bool) ([]uint32, bool) {
+	var runes []uint32
+	match := false
+
+	for _, atEnd := range []bool{false, true} {
+		seen := make(map[uint32]bool)
+		var visit func(pc uint32)
+		visit = func(pc uint32) {
+			if seen[pc] {
+				return
+			}
+			seen[pc] = true
+
+			inst := &prog.Inst[pc]
+			switch inst.Op {
+			case syntax.InstAlt, syntax.InstAltMatch:
+				visit(inst.Out)
+				visit(inst.Arg)
+			case syntax.InstCapture, syntax.InstNop:
+				visit(inst.Out)
+			case syntax.InstEmptyWidth:
+				op := syntax.EmptyOp(inst.Arg)
+				if op&(syntax.EmptyBeginText|syntax.EmptyBeginLine) != 0 && !atStart {
+					return
+				}
+				if op&(syntax.EmptyEndText|syntax.EmptyEndLine) != 0 && !atEnd {
+					return
+				}
+				visit(inst.Out)
+			case syntax.InstMatch:
+				if atEnd {
+					match = true
+				}
+			case syntax.InstFail:
+			default:
+				if !atEnd {
+					runes = append(runes, pc)
+				}
+			}
+		}
+		visit(pc)
+	}
+
+	return runes, match
+}
+
+// runeRanges returns the runes accepted by a rune instruction as lo/hi pairs
+func runeRanges(inst *syntax.Inst) []rune {
+	switch inst.Op {
+	case syntax.InstRune1:
+		return []rune{inst.Rune[0], inst.Rune[0]}
+	case syntax.InstRuneAny:
+		return []rune{0, unicode.MaxRune}
+	case syntax.InstRuneAnyNotNL:
+		return []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}
+	}
+
+	if len(inst.Rune) == 1 {
+		// Single rune, possibly case-folded
+		r := inst.Rune[0]
+		ranges := []rune{r, r}
+		if syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				ranges = append(ranges, f, f)
+			}
+		}
+		return ranges
+	}
+	return inst.Rune
+}
+
+// rangesIntersect reports whether two lo/hi range lists share a rune
+func rangesIntersect(a, b []rune) bool {
+	for i := 0; i+1 < len(a); i += 2 {
+		for j := 0; j+1 < len(b); j += 2 {
+			if a[i] <= b[j+1] && b[j] <= a[i+1] {
+				return true
+			}
+		}
+	}
+	return false
+}
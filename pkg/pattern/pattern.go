@@ -0,0 +1,172 @@
+package pattern
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a compiled branch name pattern.
+//
+// Supported syntaxes:
+//   - "main"             exact name
+//   - "feature/*"        trailing "/*" matches everything below the prefix
+//   - "release-*"        glob: "*" and "?" do not cross "/", "[...]" classes
+//   - "users/**/hotfix"  doublestar glob: "**" matches across "/"
+//   - "re:^v\d+$"        regular expression, must match the whole name
+//   - "!pattern"         negation of any of the above
+type Pattern struct {
+	raw         string
+	negate      bool
+	re          *regexp.Regexp
+	specificity int
+}
+
+// exactSpecificity ranks exact names above every wildcard pattern
+const exactSpecificity = 1 << 16
+
+// Compile parses a branch name pattern
+func Compile(raw string) (*Pattern, error) {
+	p := &Pattern{raw: raw}
+
+	expr := raw
+	if strings.HasPrefix(expr, "!") {
+		p.negate = true
+		expr = strings.TrimPrefix(expr, "!")
+	}
+	if expr == "" {
+		return nil, fmt.Errorf("invalid pattern %q: empty pattern", raw)
+	}
+
+	var source string
+	literals := 0
+	isRegexp := strings.HasPrefix(expr, "re:")
+	if isRegexp {
+		source = "^(?:" + strings.TrimPrefix(expr, "re:") + ")$"
+	} else {
+		var err error
+		if source, literals, err = globToRegexp(expr); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", raw, err)
+		}
+	}
+
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", raw, err)
+	}
+	p.re = re
+
+	// Specificity: exact names first, then by number of literal characters
+	prefix, complete := re.LiteralPrefix()
+	switch {
+	case p.negate:
+		p.specificity = 0
+	case complete:
+		p.specificity = exactSpecificity + len(prefix)
+	case isRegexp:
+		p.specificity = len(prefix)
+	default:
+		p.specificity = literals
+	}
+
+	return p, nil
+}
+
+// Match reports whether the branch name matches the pattern
+func (p *Pattern) Match(name string) bool {
+	return p.re.MatchString(name) != p.negate
+}
+
+// Negated reports whether the pattern starts with "!"
+func (p *Pattern) Negated() bool {
+	return p.negate
+}
+
+// Specificity ranks how narrowly the pattern selects branches. Exact names
+// rank highest, wildcard patterns rank by their number of literal characters
+// (for regular expressions, the length of their literal prefix) and negated
+// patterns rank lowest.
+func (p *Pattern) Specificity() int {
+	return p.specificity
+}
+
+// String returns the pattern source
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// globToRegexp converts a glob (with "**" support) into an anchored regular
+// expression and counts its literal characters
+func globToRegexp(glob string) (string, int, error) {
+	// Legacy prefix semantics: "feature/*" matches any depth below "feature/"
+	if prefix := strings.TrimSuffix(glob, "/*"); prefix != glob && !strings.ContainsAny(prefix, "*?[\\") {
+		return "^" + regexp.QuoteMeta(prefix) + "/.*$", len(prefix) + 1, nil
+	}
+
+	literals := 0
+	var b strings.Builder
+	b.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			start := i
+			for i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+			}
+			if i == start {
+				b.WriteString("[^/]*")
+				break
+			}
+			segmentStart := start == 0 || glob[start-1] == '/'
+			switch {
+			case segmentStart && i+1 < len(glob) && glob[i+1] == '/':
+				// "**/" matches zero or more leading segments
+				b.WriteString("(?:.*/)?")
+				i++
+			case segmentStart && start > 0 && i+1 == len(glob):
+				// Trailing "/**" also matches the parent itself
+				prefix := strings.TrimSuffix(b.String(), "/")
+				b.Reset()
+				b.WriteString(prefix + "(?:/.*)?")
+			default:
+				b.WriteString(".*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			// A "]" right after "[" or "[!" is a literal member of the class
+			j := i + 1
+			if j < len(glob) && (glob[j] == '!' || glob[j] == '^') {
+				j++
+			}
+			if j < len(glob) && glob[j] == ']' {
+				j++
+			}
+			end := strings.IndexByte(glob[j:], ']')
+			if end < 0 {
+				return "", 0, errors.New("unterminated character class")
+			}
+			class := glob[i+1 : j+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = j + end
+		case '\\':
+			if i+1 >= len(glob) {
+				return "", 0, errors.New("trailing escape character")
+			}
+			i++
+			literals++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			literals++
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String(), literals, nil
+}
@@ -0,0 +1,164 @@
+package pattern
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileAndMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"main", "main", true},
+		{"main", "main2", false},
+		{"feature/*", "feature/a/b", true},
+		{"release-?", "release-1", true},
+		{"release-?", "release-10", false},
+		{"release-[0-9]", "release-7", true},
+		{"release-[!0-9]", "release-7", false},
+		{"release-[]x]", "release-]", true},
+		{"**", "any/thing", true},
+		{"**/hotfix", "hotfix", true},
+		{"**/hotfix", "a/b/hotfix", true},
+		{"a/**/b", "a/x/y/b", true},
+		{`release\*`, "release*", true},
+		{`release\*`, "release1", false},
+		{"re:feature/[A-Z]+-\\d+-.*", "feature/JIRA-12-login", true},
+		{"!re:main|master", "master", false},
+		{"!re:main|master", "develop", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			p, err := Compile(tt.pattern)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			if got := p.Match(tt.name); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, raw := range []string{"", "!", "re:(", "wip/[abc", `trailing\`} {
+		if _, err := Compile(raw); err == nil {
+			t.Errorf("Expected error for pattern %q", raw)
+		}
+	}
+}
+
+func TestMatchList(t *testing.T) {
+	set := NewSet()
+
+	tests := []struct {
+		name     string
+		patterns []string
+		branch   string
+		matched  string
+		ok       bool
+	}{
+		{"positive match", []string{"main", "feature/*"}, "feature/a", "feature/*", true},
+		{"excluded", []string{"feature/*", "!feature/wip-*"}, "feature/wip-x", "", false},
+		{"not excluded", []string{"feature/*", "!feature/wip-*"}, "feature/x", "feature/*", true},
+		{"only negations", []string{"!main"}, "develop", "!main", true},
+		{"only negations excluded", []string{"!main"}, "main", "", false},
+		{"empty list", nil, "main", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, ok := set.MatchList(tt.branch, tt.patterns)
+			if matched != tt.matched || ok != tt.ok {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.matched, tt.ok, matched, ok)
+			}
+		})
+	}
+}
+
+func TestMatchAll(t *testing.T) {
+	set := NewSet()
+
+	tests := []struct {
+		name     string
+		patterns []string
+		branch   string
+		matched  string
+	}{
+		{"every positive match", []string{"wip/*", "main", "re:wip/.+"}, "wip/x", "wip/*,re:wip/.+"},
+		{"excluded", []string{"wip/*", "re:wip/.+", "!wip/keep"}, "wip/keep", ""},
+		{"only negations", []string{"!main"}, "develop", "!main"},
+		{"no match", []string{"wip/*"}, "main", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matched := strings.Join(set.MatchAll(tt.branch, tt.patterns), ","); matched != tt.matched {
+				t.Errorf("Expected %q, got %q", tt.matched, matched)
+			}
+		})
+	}
+}
+
+func TestSpecificity(t *testing.T) {
+	// Each pattern must rank strictly above the next one
+	ordered := []string{"feature/login", "re:feature/login", "feature/log*", "feature/*", "feature/**", "re:feat.*", "!main"}
+
+	for i := 0; i+1 < len(ordered); i++ {
+		a, err := Compile(ordered[i])
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		b, err := Compile(ordered[i+1])
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		if a.Specificity() < b.Specificity() {
+			t.Errorf("Expected %s (%d) to be at least as specific as %s (%d)",
+				a, a.Specificity(), b, b.Specificity())
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"main", "master", false},
+		{"main", "main", true},
+		{"feature/*", "feature/login", true},
+		{"feature/*", "bugfix/*", false},
+		{"feature/*", "*/login", true},
+		{"release-*", "release/*", false},
+		{"users/*/hotfix-*", "users/**", true},
+		{"re:v\\d+", "v1*", true},
+		{"re:v\\d+", "va*", false},
+		{"re:(?i)MAIN", "main", true},
+		{"!main", "main", false},
+		{"!main", "develop", true},
+		{"!main", "feature/*", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			a, err := Compile(tt.a)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			b, err := Compile(tt.b)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			if got := Overlaps(a, b); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if got := Overlaps(b, a); got != tt.expected {
+				t.Errorf("Expected symmetric result %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
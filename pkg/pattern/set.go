@@ -0,0 +1,97 @@
+package pattern
+
+// Set holds compiled patterns keyed by their source text so that each
+// pattern in a configuration is compiled exactly once
+type Set map[string]*Pattern
+
+// NewSet creates an empty pattern set
+func NewSet() Set {
+	return make(Set)
+}
+
+// Add compiles a pattern and stores it in the set
+func (s Set) Add(raw string) error {
+	if _, exists := s[raw]; exists {
+		return nil
+	}
+	p, err := Compile(raw)
+	if err != nil {
+		return err
+	}
+	s[raw] = p
+	return nil
+}
+
+// Get returns the compiled pattern for raw. Patterns that were not added
+// up front are compiled on demand; invalid ones never match.
+func (s Set) Get(raw string) *Pattern {
+	if p, exists := s[raw]; exists {
+		return p
+	}
+	if p, err := Compile(raw); err == nil {
+		return p
+	}
+	return nil
+}
+
+// Match reports whether the branch name matches a single pattern
+func (s Set) Match(name, raw string) bool {
+	p := s.Get(raw)
+	return p != nil && p.Match(name)
+}
+
+// MatchList evaluates a list of patterns with exclusion semantics: the name
+// matches if it matches at least one positive pattern (or the list only has
+// negated patterns) and is not excluded by any negated pattern. It returns the
+// pattern responsible for the match.
+func (s Set) MatchList(name string, raws []string) (string, bool) {
+	var matched string
+	hasPositive := false
+	firstNegated := ""
+
+	for _, raw := range raws {
+		p := s.Get(raw)
+		if p == nil {
+			continue
+		}
+		if p.Negated() {
+			if !p.Match(name) {
+				return "", false
+			}
+			if firstNegated == "" {
+				firstNegated = raw
+			}
+			continue
+		}
+		hasPositive = true
+		if matched == "" && p.Match(name) {
+			matched = raw
+		}
+	}
+
+	if !hasPositive {
+		return firstNegated, firstNegated != ""
+	}
+	return matched, matched != ""
+}
+
+// MatchAll evaluates a list of patterns like MatchList but returns every
+// positive pattern the name matches rather than only the first. It returns
+// nil when the name does not match the list.
+func (s Set) MatchAll(name string, raws []string) []string {
+	first, ok := s.MatchList(name, raws)
+	if !ok {
+		return nil
+	}
+
+	var matched []string
+	for _, raw := range raws {
+		if p := s.Get(raw); p != nil && !p.Negated() && p.Match(name) {
+			matched = append(matched, raw)
+		}
+	}
+	if len(matched) == 0 {
+		return []string{first}
+	}
+	return matched
+}
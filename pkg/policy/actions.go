@@ -0,0 +1,165 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+)
+
+// PlanStage is a group of actions that can run once every earlier stage is done
+type PlanStage struct {
+	Actions []PlannedAction `json:"actions" yaml:"actions"`
+}
+
+// PlannedAction is an action of a plan stage with its catalog settings
+type PlannedAction struct {
+	Name   string            `json:"name" yaml:"name"`
+	Needs  []string          `json:"needs,omitempty" yaml:"needs,omitempty"`
+	Params map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// builtinActions are set by the engine itself and need no catalog entry
+var builtinActions = []string{"teardown"}
+
+// compileActions checks that the dependencies in the action catalog name
+// known actions and do not form cycles
+func compileActions(cfg *config.Config) []error {
+	var errs []error
+	names := sortedKeys(cfg.Actions)
+	for _, name := range names {
+		for _, dep := range cfg.Actions[name].Needs {
+			if _, exists := cfg.Actions[dep]; !exists {
+				errs = append(errs, fmt.Errorf("actions.%s.needs: unknown action %q", name, dep))
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(names))
+	var stack []string
+	var visit func(name string)
+	visit = func(name string) {
+		switch state[name] {
+		case done:
+			return
+		case visiting:
+			for i, n := range stack {
+				if n == name {
+					cycle := append(append([]string{}, stack[i:]...), name)
+					errs = append(errs, fmt.Errorf("actions.%s.needs: dependency cycle %s", name, strings.Join(cycle, " -> ")))
+					break
+				}
+			}
+			return
+		}
+
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range cfg.Actions[name].Needs {
+			if _, exists := cfg.Actions[dep]; exists {
+				visit(dep)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+	for _, name := range names {
+		visit(name)
+	}
+
+	return errs
+}
+
+// validateActionReferences reports actions named in the configuration that
+// are missing from the action catalog. Without a catalog any action is allowed.
+func validateActionReferences(cfg *config.Config) []string {
+	if len(cfg.Actions) == 0 {
+		return nil
+	}
+
+	var problems []string
+	check := func(location string, actions []string) {
+		for _, action := range actions {
+			if _, exists := cfg.Actions[action]; !exists && !contains(builtinActions, action) {
+				problems = append(problems, fmt.Sprintf("%s: unknown action %q", location, action))
+			}
+		}
+	}
+
+	for i, mapping := range cfg.BranchMappings {
+		check(fmt.Sprintf("branch_mappings[%d].actions", i), mapping.Actions)
+	}
+	for i, rule := range cfg.Policies.Rules {
+		check(fmt.Sprintf("policies.rules[%d].add_actions", i), rule.AddActions)
+		check(fmt.Sprintf("policies.rules[%d].remove_actions", i), rule.RemoveActions)
+	}
+	if cfg.Risk != nil {
+		for i, threshold := range cfg.Risk.Thresholds {
+			check(fmt.Sprintf("risk.thresholds[%d].add_actions", i), threshold.AddActions)
+		}
+	}
+	return problems
+}
+
+// applyActions orders the decision's actions after their dependencies in the
+// action catalog, adding dependencies that are missing, and groups them into
+// the stages of the decision's plan
+func (e *Engine) applyActions(decision *Decision, trace *Trace) {
+	catalog := e.config.Actions
+	if len(catalog) == 0 {
+		return
+	}
+
+	var ordered []string
+	placed := make(map[string]bool)
+	var place func(name string)
+	place = func(name string) {
+		if placed[name] {
+			return
+		}
+		placed[name] = true
+		for _, dep := range catalog[name].Needs {
+			place(dep)
+		}
+		ordered = append(ordered, name)
+	}
+	for _, action := range decision.Actions {
+		if _, exists := catalog[action]; !exists && !contains(builtinActions, action) {
+			decision.addFinding(SeverityWarn, FindingUnknownAction,
+				fmt.Sprintf("Action %s is not in the action catalog", action))
+		}
+		place(action)
+	}
+
+	if ordered == nil {
+		ordered = []string{}
+	}
+	if strings.Join(ordered, ",") != strings.Join(decision.Actions, ",") {
+		trace.change("actions", "actions", decision.Actions, ordered, "ordered after their dependencies in the action catalog")
+	}
+	decision.Actions = ordered
+
+	// An action's stage comes after the stages of all its dependencies
+	stages := make(map[string]int, len(ordered))
+	var plan []PlanStage
+	for _, name := range ordered {
+		for _, dep := range catalog[name].Needs {
+			if stages[dep]+1 > stages[name] {
+				stages[name] = stages[dep] + 1
+			}
+		}
+		for len(plan) <= stages[name] {
+			plan = append(plan, PlanStage{})
+		}
+		plan[stages[name]].Actions = append(plan[stages[name]].Actions, PlannedAction{
+			Name:   name,
+			Needs:  catalog[name].Needs,
+			Params: catalog[name].Params,
+		})
+	}
+	decision.Plan = plan
+}
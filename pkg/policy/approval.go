@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+)
+
+// Approval describes who must approve a deployment
+type Approval struct {
+	Required          int      `json:"required" yaml:"required"`
+	Teams             []string `json:"teams,omitempty" yaml:"teams,omitempty"`
+	Users             []string `json:"users,omitempty" yaml:"users,omitempty"`
+	AllowSelfApproval bool     `json:"allow_self_approval" yaml:"allow_self_approval"`
+}
+
+// Reviewers returns the teams and users that may approve
+func (a *Approval) Reviewers() []string {
+	if a == nil {
+		return nil
+	}
+	return append(append([]string{}, a.Teams...), a.Users...)
+}
+
+// String describes the approval requirement, e.g. "2 approvals from ops, alice"
+func (a *Approval) String() string {
+	if a == nil {
+		return ""
+	}
+	s := fmt.Sprintf("%d approval", a.Required)
+	if a.Required != 1 {
+		s += "s"
+	}
+	if reviewers := a.Reviewers(); len(reviewers) > 0 {
+		s += " from " + strings.Join(reviewers, ", ")
+	}
+	if !a.AllowSelfApproval {
+		s += "; self-approval not allowed"
+	}
+	return s
+}
+
+// newApproval returns the approval requirement of an environment, requiring
+// one approval when no count is given
+func newApproval(cfg *config.ApprovalConfig) *Approval {
+	approval := &Approval{
+		Required:          cfg.Required,
+		Teams:             cfg.Teams,
+		Users:             cfg.Users,
+		AllowSelfApproval: cfg.AllowSelfApproval,
+	}
+	if approval.Required < 1 {
+		approval.Required = 1
+	}
+	return approval
+}
+
+// applyApproval makes the approval requirement match RequiresApproval: a
+// decision that requires approval without an environment requirement needs
+// one approval from anyone, and one that does not require approval has none
+func applyApproval(decision *Decision) {
+	switch {
+	case !decision.RequiresApproval:
+		decision.Approval = nil
+	case decision.Approval == nil:
+		decision.Approval = &Approval{Required: 1}
+	}
+}
+
+// validateApproval checks the approval requirement of an environment
+func validateApproval(name string, cfg *config.ApprovalConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var problems []string
+	if cfg.Required < 0 {
+		problems = append(problems, fmt.Sprintf("environments.%s.approval.required: must not be negative", name))
+	}
+	if reviewers := len(cfg.Users); len(cfg.Teams) == 0 && reviewers > 0 && cfg.Required > reviewers {
+		problems = append(problems, fmt.Sprintf(
+			"environments.%s.approval.required: %d approvals required but only %d users may approve", name, cfg.Required, reviewers))
+	}
+	return problems
+}
@@ -0,0 +1,247 @@
+package policy
+
+import "github.com/NadeeshaMedagama/branch_aware_ci/pkg/interfaces"
+
+// DecisionFromInterfaces converts the service decision type into a Decision
+func DecisionFromInterfaces(d *interfaces.Decision) *Decision {
+	if d == nil {
+		return nil
+	}
+
+	return &Decision{
+		BranchName:       d.BranchName,
+		BranchType:       d.BranchType,
+		CommitSHA:        d.CommitSHA,
+		Environment:      d.Environment,
+		ShouldDeploy:     d.ShouldDeploy,
+		RequiresApproval: d.RequiresApproval,
+		Approval:         approvalFromInterfaces(d.Approval),
+		Risk:             riskFromInterfaces(d.Risk),
+		Ephemeral:        d.Ephemeral,
+		Teardown:         d.Teardown,
+		Actions:          d.Actions,
+		Plan:             planFromInterfaces(d.Plan),
+		Variables:        d.Variables,
+		Warnings:         d.Warnings,
+		Findings:         findingsFromInterfaces(d.Findings),
+		Metadata:         d.Metadata,
+		Environments:     environmentsFromInterfaces(d.Environments),
+		Trace:            traceFromInterfaces(d.Trace),
+	}
+}
+
+// ToInterfaces converts the Decision into the service decision type
+func (d *Decision) ToInterfaces() *interfaces.Decision {
+	if d == nil {
+		return nil
+	}
+
+	return &interfaces.Decision{
+		BranchName:       d.BranchName,
+		BranchType:       d.BranchType,
+		CommitSHA:        d.CommitSHA,
+		Environment:      d.Environment,
+		ShouldDeploy:     d.ShouldDeploy,
+		RequiresApproval: d.RequiresApproval,
+		Approval:         d.Approval.toInterfaces(),
+		Risk:             d.Risk.toInterfaces(),
+		Ephemeral:        d.Ephemeral,
+		Teardown:         d.Teardown,
+		Actions:          d.Actions,
+		Plan:             planToInterfaces(d.Plan),
+		Variables:        d.Variables,
+		Warnings:         d.Warnings,
+		Findings:         findingsToInterfaces(d.Findings),
+		Metadata:         d.Metadata,
+		Environments:     environmentsToInterfaces(d.Environments),
+		Trace:            d.Trace.toInterfaces(),
+	}
+}
+
+// environmentsFromInterfaces converts service per-environment decisions into EnvironmentDecisions
+func environmentsFromInterfaces(environments []interfaces.EnvironmentDecision) []EnvironmentDecision {
+	var result []EnvironmentDecision
+	for _, env := range environments {
+		result = append(result, EnvironmentDecision{
+			Environment:      env.Environment,
+			ShouldDeploy:     env.ShouldDeploy,
+			RequiresApproval: env.RequiresApproval,
+			Approval:         approvalFromInterfaces(env.Approval),
+			Risk:             riskFromInterfaces(env.Risk),
+			Ephemeral:        env.Ephemeral,
+			Teardown:         env.Teardown,
+			Actions:          env.Actions,
+			Variables:        env.Variables,
+			Warnings:         env.Warnings,
+			Findings:         findingsFromInterfaces(env.Findings),
+		})
+	}
+	return result
+}
+
+// environmentsToInterfaces converts EnvironmentDecisions into the service type
+func environmentsToInterfaces(environments []EnvironmentDecision) []interfaces.EnvironmentDecision {
+	var result []interfaces.EnvironmentDecision
+	for _, env := range environments {
+		result = append(result, interfaces.EnvironmentDecision{
+			Environment:      env.Environment,
+			ShouldDeploy:     env.ShouldDeploy,
+			RequiresApproval: env.RequiresApproval,
+			Approval:         env.Approval.toInterfaces(),
+			Risk:             env.Risk.toInterfaces(),
+			Ephemeral:        env.Ephemeral,
+			Teardown:         env.Teardown,
+			Actions:          env.Actions,
+			Variables:        env.Variables,
+			Warnings:         env.Warnings,
+			Findings:         findingsToInterfaces(env.Findings),
+		})
+	}
+	return result
+}
+
+// approvalFromInterfaces converts the service approval type into an Approval
+func approvalFromInterfaces(a *interfaces.Approval) *Approval {
+	if a == nil {
+		return nil
+	}
+	approval := Approval(*a)
+	return &approval
+}
+
+// toInterfaces converts the Approval into the service approval type
+func (a *Approval) toInterfaces() *interfaces.Approval {
+	if a == nil {
+		return nil
+	}
+	approval := interfaces.Approval(*a)
+	return &approval
+}
+
+// riskFromInterfaces converts the service risk type into a Risk
+func riskFromInterfaces(r *interfaces.Risk) *Risk {
+	if r == nil {
+		return nil
+	}
+	risk := &Risk{Score: r.Score, Level: r.Level}
+	for _, f := range r.Factors {
+		risk.Factors = append(risk.Factors, RiskFactor(f))
+	}
+	return risk
+}
+
+// toInterfaces converts the Risk into the service risk type
+func (r *Risk) toInterfaces() *interfaces.Risk {
+	if r == nil {
+		return nil
+	}
+	risk := &interfaces.Risk{Score: r.Score, Level: r.Level}
+	for _, f := range r.Factors {
+		risk.Factors = append(risk.Factors, interfaces.RiskFactor(f))
+	}
+	return risk
+}
+
+// planFromInterfaces converts service plan stages into PlanStages
+func planFromInterfaces(plan []interfaces.PlanStage) []PlanStage {
+	var result []PlanStage
+	for _, stage := range plan {
+		var actions []PlannedAction
+		for _, a := range stage.Actions {
+			actions = append(actions, PlannedAction(a))
+		}
+		result = append(result, PlanStage{Actions: actions})
+	}
+	return result
+}
+
+// planToInterfaces converts PlanStages into the service plan type
+func planToInterfaces(plan []PlanStage) []interfaces.PlanStage {
+	var result []interfaces.PlanStage
+	for _, stage := range plan {
+		var actions []interfaces.PlannedAction
+		for _, a := range stage.Actions {
+			actions = append(actions, interfaces.PlannedAction(a))
+		}
+		result = append(result, interfaces.PlanStage{Actions: actions})
+	}
+	return result
+}
+
+// findingsFromInterfaces converts service findings into Findings
+func findingsFromInterfaces(findings []interfaces.Finding) []Finding {
+	if findings == nil {
+		return nil
+	}
+	result := make([]Finding, 0, len(findings))
+	for _, f := range findings {
+		result = append(result, Finding{Severity: Severity(f.Severity), Code: f.Code, Message: f.Message})
+	}
+	return result
+}
+
+// findingsToInterfaces converts Findings into the service finding type
+func findingsToInterfaces(findings []Finding) []interfaces.Finding {
+	if findings == nil {
+		return nil
+	}
+	result := make([]interfaces.Finding, 0, len(findings))
+	for _, f := range findings {
+		result = append(result, interfaces.Finding{Severity: string(f.Severity), Code: f.Code, Message: f.Message})
+	}
+	return result
+}
+
+// traceFromInterfaces converts the service trace type into a Trace
+func traceFromInterfaces(t *interfaces.DecisionTrace) *Trace {
+	if t == nil {
+		return nil
+	}
+
+	trace := &Trace{}
+	for _, m := range t.Mappings {
+		trace.Mappings = append(trace.Mappings, MappingTrace(m))
+	}
+	if t.Winner != nil {
+		winner := MappingTrace(*t.Winner)
+		trace.Winner = &winner
+	}
+	if t.AllowedBranch != nil {
+		allowed := AllowedTrace(*t.AllowedBranch)
+		trace.AllowedBranch = &allowed
+	}
+	for _, c := range t.Changes {
+		trace.Changes = append(trace.Changes, ChangeTrace(c))
+	}
+	for _, v := range t.Variables {
+		trace.Variables = append(trace.Variables, VariableTrace(v))
+	}
+	return trace
+}
+
+// toInterfaces converts the Trace into the service trace type
+func (t *Trace) toInterfaces() *interfaces.DecisionTrace {
+	if t == nil {
+		return nil
+	}
+
+	trace := &interfaces.DecisionTrace{}
+	for _, m := range t.Mappings {
+		trace.Mappings = append(trace.Mappings, interfaces.MappingTrace(m))
+	}
+	if t.Winner != nil {
+		winner := interfaces.MappingTrace(*t.Winner)
+		trace.Winner = &winner
+	}
+	if t.AllowedBranch != nil {
+		allowed := interfaces.AllowedTrace(*t.AllowedBranch)
+		trace.AllowedBranch = &allowed
+	}
+	for _, c := range t.Changes {
+		trace.Changes = append(trace.Changes, interfaces.ChangeTrace(c))
+	}
+	for _, v := range t.Variables {
+		trace.Variables = append(trace.Variables, interfaces.VariableTrace(v))
+	}
+	return trace
+}
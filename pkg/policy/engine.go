@@ -1,133 +1,633 @@
 package policy
 
 import (
+	"errors"
 	"fmt"
-	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/expr"
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/pattern"
 )
 
 // Decision represents a CI/CD decision based on branch analysis
 type Decision struct {
-	BranchName       string            `json:"branch_name" yaml:"branch_name"`
-	BranchType       string            `json:"branch_type" yaml:"branch_type"`
+	BranchName       string                `json:"branch_name" yaml:"branch_name"`
+	BranchType       string                `json:"branch_type" yaml:"branch_type"`
+	CommitSHA        string                `json:"commit_sha,omitempty" yaml:"commit_sha,omitempty"`
+	Environment      string                `json:"environment" yaml:"environment"`
+	ShouldDeploy     bool                  `json:"should_deploy" yaml:"should_deploy"`
+	RequiresApproval bool                  `json:"requires_approval" yaml:"requires_approval"`
+	Approval         *Approval             `json:"approval,omitempty" yaml:"approval,omitempty"`
+	Risk             *Risk                 `json:"risk,omitempty" yaml:"risk,omitempty"`
+	Ephemeral        bool                  `json:"ephemeral,omitempty" yaml:"ephemeral,omitempty"`
+	Teardown         bool                  `json:"teardown,omitempty" yaml:"teardown,omitempty"`
+	Actions          []string              `json:"actions" yaml:"actions"`
+	Plan             []PlanStage           `json:"plan,omitempty" yaml:"plan,omitempty"`
+	Variables        map[string]string     `json:"variables" yaml:"variables"`
+	Warnings         []string              `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	Findings         []Finding             `json:"findings,omitempty" yaml:"findings,omitempty"`
+	Metadata         map[string]string     `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Environments     []EnvironmentDecision `json:"environments,omitempty" yaml:"environments,omitempty"`
+	Trace            *Trace                `json:"trace,omitempty" yaml:"trace,omitempty"`
+
+	// approvalRaised records that a rule, risk threshold or plugin required
+	// approval, which holds whatever environment the decision ends up in
+	approvalRaised bool
+}
+
+// EnvironmentDecision is the decision for one environment of a mapping that
+// targets several environments
+type EnvironmentDecision struct {
 	Environment      string            `json:"environment" yaml:"environment"`
 	ShouldDeploy     bool              `json:"should_deploy" yaml:"should_deploy"`
 	RequiresApproval bool              `json:"requires_approval" yaml:"requires_approval"`
+	Approval         *Approval         `json:"approval,omitempty" yaml:"approval,omitempty"`
+	Risk             *Risk             `json:"risk,omitempty" yaml:"risk,omitempty"`
+	Ephemeral        bool              `json:"ephemeral,omitempty" yaml:"ephemeral,omitempty"`
+	Teardown         bool              `json:"teardown,omitempty" yaml:"teardown,omitempty"`
 	Actions          []string          `json:"actions" yaml:"actions"`
 	Variables        map[string]string `json:"variables" yaml:"variables"`
 	Warnings         []string          `json:"warnings,omitempty" yaml:"warnings,omitempty"`
-	Metadata         map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Findings         []Finding         `json:"findings,omitempty" yaml:"findings,omitempty"`
+}
+
+// Targets returns the per-environment decisions, or the decision itself as the
+// only environment when the mapping targets a single one
+func (d *Decision) Targets() []EnvironmentDecision {
+	if len(d.Environments) > 0 {
+		return d.Environments
+	}
+	return []EnvironmentDecision{d.environmentDecision()}
+}
+
+// environmentDecision returns the per-environment fields of the decision
+func (d *Decision) environmentDecision() EnvironmentDecision {
+	return EnvironmentDecision{
+		Environment:      d.Environment,
+		ShouldDeploy:     d.ShouldDeploy,
+		RequiresApproval: d.RequiresApproval,
+		Approval:         d.Approval,
+		Risk:             d.Risk,
+		Ephemeral:        d.Ephemeral,
+		Teardown:         d.Teardown,
+		Actions:          d.Actions,
+		Variables:        d.Variables,
+		Warnings:         d.Warnings,
+		Findings:         d.Findings,
+	}
 }
 
 // Engine evaluates policies and makes CI/CD decisions
 type Engine struct {
-	config *config.Config
+	config        *config.Config
+	patterns      pattern.Set
+	rules         []*expr.Expression
+	freezeWindows []*freezeWindow
+	templates     map[string]*template.Template
+	riskLocation  *time.Location
+	plugins       []*Plugin
+	rego          *RegoPolicy
+	history       DeploymentHistory
+	now           func() time.Time
+}
+
+// Option configures an Engine
+type Option func(*Engine)
+
+// WithClock sets the function the engine uses to read the current time
+func WithClock(now func() time.Time) Option {
+	return func(e *Engine) {
+		e.now = now
+	}
 }
 
-// NewEngine creates a new policy engine
-func NewEngine(cfg *config.Config) *Engine {
-	return &Engine{config: cfg}
+// NewEngine creates a new policy engine. All branch patterns, rule
+// expressions, freeze windows, promotion chains, environment templates, risk
+// settings and the action catalog in the configuration are compiled up front;
+// invalid ones are reported as errors.
+func NewEngine(cfg *config.Config, opts ...Option) (*Engine, error) {
+	patterns, errs := compilePatterns(cfg)
+	rules, ruleErrs := compileRules(cfg)
+	windows, windowErrs := compileFreezeWindows(cfg)
+	templates, templateErrs := compileTemplates(cfg)
+	riskLocation, riskErrs := compileRisk(cfg)
+	errs = append(append(errs, ruleErrs...), windowErrs...)
+	errs = append(append(errs, compilePromotion(cfg)...), templateErrs...)
+	errs = append(append(errs, riskErrs...), compileActions(cfg)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	e := &Engine{
+		config:        cfg,
+		patterns:      patterns,
+		rules:         rules,
+		freezeWindows: windows,
+		templates:     templates,
+		riskLocation:  riskLocation,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// Validate checks a configuration for problems and returns one message per issue
+func Validate(cfg *config.Config) []string {
+	var problems []string
+
+	// Validate environments
+	if len(cfg.Environments) == 0 {
+		problems = append(problems, "No environments defined")
+	}
+
+	// Validate branch mappings
+	if len(cfg.BranchMappings) == 0 {
+		problems = append(problems, "No branch mappings defined")
+	}
+
+	// Check that every branch pattern compiles
+	patterns, errs := compilePatterns(cfg)
+	for _, err := range errs {
+		problems = append(problems, err.Error())
+	}
+
+	// Check that every rule compiles and targets a known environment
+	_, ruleErrs := compileRules(cfg)
+	for _, err := range ruleErrs {
+		problems = append(problems, err.Error())
+	}
+	for i, rule := range cfg.Policies.Rules {
+		if _, exists := cfg.Environments[rule.SetEnvironment]; rule.SetEnvironment != "" && !exists {
+			problems = append(problems, fmt.Sprintf("policies.rules[%d].set_environment: unknown environment %q", i, rule.SetEnvironment))
+		}
+	}
+
+	// Check that every freeze window has a valid schedule and known environments
+	_, windowErrs := compileFreezeWindows(cfg)
+	for _, err := range windowErrs {
+		problems = append(problems, err.Error())
+	}
+	for i, window := range cfg.FreezeWindows {
+		for _, env := range window.Environments {
+			if _, exists := cfg.Environments[env]; !exists {
+				problems = append(problems, fmt.Sprintf("freeze_windows[%d].environments: unknown environment %q", i, env))
+			}
+		}
+	}
+
+	// Check promotion chains
+	for _, err := range compilePromotion(cfg) {
+		problems = append(problems, err.Error())
+	}
+	for i, chain := range cfg.Promotion.Chains {
+		for _, env := range chain.Environments {
+			if _, exists := cfg.Environments[env]; !exists {
+				problems = append(problems, fmt.Sprintf("promotion.chains[%d].environments: unknown environment %q", i, env))
+			}
+		}
+	}
+
+	// Check environment templates
+	_, templateErrs := compileTemplates(cfg)
+	for _, err := range templateErrs {
+		problems = append(problems, err.Error())
+	}
+
+	// Check risk settings
+	_, riskErrs := compileRisk(cfg)
+	for _, err := range riskErrs {
+		problems = append(problems, err.Error())
+	}
+	if cfg.Risk != nil {
+		for _, env := range sortedKeys(cfg.Risk.Environments) {
+			if _, exists := cfg.Environments[env]; !exists {
+				problems = append(problems, fmt.Sprintf("risk.environments: unknown environment %q", env))
+			}
+		}
+	}
+
+	// Check plugin settings
+	_, pluginErrs := compilePlugins(cfg.Plugins)
+	for _, err := range pluginErrs {
+		problems = append(problems, err.Error())
+	}
+
+	// Check the action catalog and the actions named elsewhere
+	for _, err := range compileActions(cfg) {
+		problems = append(problems, err.Error())
+	}
+	problems = append(problems, validateActionReferences(cfg)...)
+
+	// Check approval requirements
+	for _, name := range sortedEnvironmentNames(cfg) {
+		problems = append(problems, validateApproval(name, cfg.Environments[name].Approval)...)
+	}
+
+	// Check that mappings name their environments once
+	for i, mapping := range cfg.BranchMappings {
+		if mapping.Environment != "" && len(mapping.Environments) > 0 {
+			problems = append(problems, fmt.Sprintf("branch_mappings[%d]: use either environment or environments", i))
+		}
+		for _, env := range mapping.Environments {
+			if _, exists := cfg.Environments[env]; !exists {
+				problems = append(problems, fmt.Sprintf("branch_mappings[%d].environments: unknown environment %q", i, env))
+			}
+		}
+	}
+
+	// Check for conflicting mappings: overlapping patterns with equal priority
+	// that map to different environments
+	mappings := cfg.BranchMappings
+	for i := range mappings {
+		for j := i + 1; j < len(mappings); j++ {
+			a, b := mappings[i], mappings[j]
+			if a.Priority != b.Priority || equalTargets(a.Targets(), b.Targets()) {
+				continue
+			}
+			pa, pb := patterns[a.Pattern], patterns[b.Pattern]
+			if pa == nil || pb == nil || !pattern.Overlaps(pa, pb) {
+				continue
+			}
+
+			winner := fmt.Sprintf("branch_mappings[%d] wins by declaration order", i)
+			if pb.Specificity() > pa.Specificity() {
+				winner = fmt.Sprintf("branch_mappings[%d] wins by specificity", j)
+			} else if pa.Specificity() > pb.Specificity() {
+				winner = fmt.Sprintf("branch_mappings[%d] wins by specificity", i)
+			}
+			problems = append(problems, fmt.Sprintf(
+				"Conflicting mappings at priority %d: branch_mappings[%d] (%s → %s) and branch_mappings[%d] (%s → %s) overlap; %s",
+				a.Priority, i, a.Pattern, strings.Join(a.Targets(), ", "), j, b.Pattern, strings.Join(b.Targets(), ", "), winner))
+		}
+	}
+
+	return problems
+}
+
+// compilePatterns compiles every branch pattern referenced by the configuration
+func compilePatterns(cfg *config.Config) (pattern.Set, []error) {
+	patterns := pattern.NewSet()
+	var errs []error
+
+	add := func(location, raw string) {
+		if err := patterns.Add(raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", location, err))
+		}
+	}
+
+	for i, mapping := range cfg.BranchMappings {
+		add(fmt.Sprintf("branch_mappings[%d].pattern", i), mapping.Pattern)
+	}
+
+	for _, name := range sortedEnvironmentNames(cfg) {
+		for i, raw := range cfg.Environments[name].AllowedBranches {
+			add(fmt.Sprintf("environments.%s.allowed_branches[%d]", name, i), raw)
+		}
+	}
+
+	for i, raw := range cfg.Policies.BlockedBranchPatterns {
+		add(fmt.Sprintf("policies.blocked_branch_patterns[%d]", i), raw)
+	}
+
+	for i, raw := range cfg.Policies.AutoDeployBranches {
+		add(fmt.Sprintf("policies.auto_deploy_branches[%d]", i), raw)
+	}
+
+	for i, window := range cfg.FreezeWindows {
+		for j, raw := range window.ExemptBranches {
+			add(fmt.Sprintf("freeze_windows[%d].exempt_branches[%d]", i, j), raw)
+		}
+	}
+
+	if cfg.Risk != nil {
+		for i, path := range cfg.Risk.SensitivePaths {
+			add(fmt.Sprintf("risk.sensitive_paths[%d].pattern", i), path.Pattern)
+		}
+	}
+
+	return patterns, errs
 }
 
 // Evaluate evaluates the branch and returns a decision
 func (e *Engine) Evaluate(branchInfo *git.BranchInfo) (*Decision, error) {
+	return e.evaluate(branchInfo, nil)
+}
+
+// Explain evaluates the branch like Evaluate and records a trace of how the
+// decision was reached in Decision.Trace
+func (e *Engine) Explain(branchInfo *git.BranchInfo) (*Decision, error) {
+	trace := &Trace{Mappings: []MappingTrace{}}
+	decision, err := e.evaluate(branchInfo, trace)
+	if err != nil {
+		return nil, err
+	}
+	decision.Trace = trace
+	return decision, nil
+}
+
+// NeedsLineStats reports whether decisions read the number of lines changed
+// by the commit: to score risk, in a rule, or in the branch passed to plugins
+// and Rego policies, which may read it
+func (e *Engine) NeedsLineStats() bool {
+	if e.config.Risk != nil && e.config.Risk.Changes.PerHundredLines != 0 {
+		return true
+	}
+	for _, rule := range e.config.Policies.Rules {
+		if strings.Contains(rule.When, "lines_changed") {
+			return true
+		}
+	}
+	return len(e.plugins) > 0 || e.rego != nil
+}
+
+// evaluate makes the decision, recording each step in trace when it is
+// non-nil. A mapping that targets several environments is evaluated once per
+// environment; the trace covers the first one.
+func (e *Engine) evaluate(branchInfo *git.BranchInfo, trace *Trace) (*Decision, error) {
+	// Find matching branch mapping
+	index := e.bestMappingIndex(branchInfo.ShortName)
+	if trace != nil {
+		e.traceMappings(trace, branchInfo.ShortName, index)
+	}
+	if index < 0 {
+		return e.evaluateEnvironment(branchInfo, index, "", trace)
+	}
+
+	targets := e.config.BranchMappings[index].Targets()
+	if len(targets) == 1 {
+		return e.evaluateEnvironment(branchInfo, index, targets[0], trace)
+	}
+
+	var decision *Decision
+	for i, target := range targets {
+		var targetTrace *Trace
+		if i == 0 {
+			targetTrace = trace
+		}
+		d, err := e.evaluateEnvironment(branchInfo, index, target, targetTrace)
+		if err != nil {
+			return nil, err
+		}
+		if decision == nil {
+			first := *d
+			decision = &first
+			decision.Warnings = []string{}
+			decision.Findings = nil
+		}
+		decision.merge(d)
+	}
+	return decision, nil
+}
+
+// merge adds an environment's decision to a multi-environment decision.
+// The decision deploys or requires approval if any of its environments does,
+// taking the approval requirement of the first that requires one and the
+// highest risk score, and lists the findings of all of them once.
+func (d *Decision) merge(env *Decision) {
+	d.Environments = append(d.Environments, env.environmentDecision())
+	d.ShouldDeploy = d.ShouldDeploy || env.ShouldDeploy
+	d.RequiresApproval = d.RequiresApproval || env.RequiresApproval
+	if d.Approval == nil {
+		d.Approval = env.Approval
+	}
+	if env.Risk != nil && (d.Risk == nil || env.Risk.Score > d.Risk.Score) {
+		d.Risk = env.Risk
+	}
+	for _, f := range env.Findings {
+		if !containsFinding(d.Findings, f) {
+			d.addFinding(f.Severity, f.Code, f.Message)
+		}
+	}
+}
+
+// containsFinding checks if a finding is already listed
+func containsFinding(findings []Finding, finding Finding) bool {
+	for _, f := range findings {
+		if f == finding {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateEnvironment makes the decision for one target environment of the
+// mapping at index, or the default decision when index is negative
+func (e *Engine) evaluateEnvironment(branchInfo *git.BranchInfo, index int, target string, trace *Trace) (*Decision, error) {
 	decision := &Decision{
 		BranchName: branchInfo.ShortName,
 		BranchType: branchInfo.Type,
+		CommitSHA:  branchInfo.CommitSHA,
 		Actions:    []string{},
 		Variables:  make(map[string]string),
 		Warnings:   []string{},
 		Metadata:   branchInfo.Metadata,
 	}
 
-	// Find matching branch mapping
-	mapping := e.findBestMapping(branchInfo.ShortName)
-	if mapping == nil {
+	if index < 0 {
 		decision.Environment = "development"
 		decision.ShouldDeploy = false
-		decision.Warnings = append(decision.Warnings, "No matching branch mapping found, using development environment")
+		decision.addFinding(SeverityWarn, FindingNoMapping, "No matching branch mapping found, using development environment")
+		trace.change("default", "environment", "", decision.Environment, "no branch mapping matched")
 	} else {
-		decision.Environment = mapping.Environment
+		mapping := e.config.BranchMappings[index]
+		source := fmt.Sprintf("branch_mappings[%d]", index)
+		decision.Environment = target
 		decision.Actions = mapping.Actions
-		decision.ShouldDeploy = e.shouldDeploy(mapping.Actions, branchInfo.ShortName)
+		trace.change(source, "environment", "", decision.Environment, "matching mapping with the highest priority, then specificity, then earliest declaration")
+		trace.change(source, "actions", []string{}, decision.Actions, "mapping actions")
+
+		deploy, reason := e.shouldDeploy(mapping.Actions, branchInfo.ShortName)
+		decision.ShouldDeploy = deploy
+		trace.change(source, "should_deploy", false, deploy, reason)
 	}
 
 	// Apply environment configuration
-	if envConfig, exists := e.config.Environments[decision.Environment]; exists {
-		decision.RequiresApproval = envConfig.RequiresApproval
-		for k, v := range envConfig.Variables {
-			decision.Variables[k] = v
-		}
+	if err := e.applyEnvironment(decision, branchInfo, trace); err != nil {
+		return nil, err
+	}
 
-		// Check if branch is allowed for this environment
-		if !e.isBranchAllowed(branchInfo.ShortName, envConfig.AllowedBranches) {
-			decision.Warnings = append(decision.Warnings,
-				fmt.Sprintf("Branch %s may not be allowed to deploy to %s",
-					branchInfo.ShortName, decision.Environment))
-		}
+	if branchInfo.CommitMissing != "" && !branchInfo.Deleted {
+		decision.addFinding(SeverityWarn, FindingCommitMissing,
+			fmt.Sprintf("Commit data for %s is missing (%s); rules, risk and plugins see no commit, changed files or lines",
+				branchInfo.ShortName, branchInfo.CommitMissing))
 	}
 
 	// Apply policies
-	e.applyPolicies(decision, branchInfo)
+	e.applyPolicies(decision, branchInfo, trace)
+
+	// Apply conditional rules
+	now := e.now()
+	if err := e.applyRules(decision, branchInfo, now, trace); err != nil {
+		return nil, err
+	}
+
+	// Score the risk, check promotion and freeze windows against the final
+	// environment and run plugins on the draft decision; they gate deployments
+	// and do not apply to deleted branches
+	if !branchInfo.Deleted {
+		e.applyRisk(decision, branchInfo, now, trace)
+		e.applyPromotion(decision, trace)
+		e.applyFreezeWindows(decision, branchInfo, now, trace)
+		e.applyPlugins(decision, branchInfo, trace)
+	}
+
+	// Render template environments, then tear them down for deleted branches
+	if err := e.applyTemplate(decision, branchInfo, trace); err != nil {
+		return nil, err
+	}
+	applyTeardown(decision, branchInfo, trace)
+	e.applyActions(decision, trace)
+	applyApproval(decision)
+
+	// The Rego policy is layered on top of the built-in decision
+	if e.rego != nil {
+		if err := e.applyRego(decision, branchInfo, trace); err != nil {
+			return nil, err
+		}
+		applyApproval(decision)
+	}
 
 	return decision, nil
 }
 
+// applyEnvironment applies the settings of the decision's environment,
+// replacing any variables and approval requirement set for a previous one
+func (e *Engine) applyEnvironment(decision *Decision, branchInfo *git.BranchInfo, trace *Trace) error {
+	decision.Variables = make(map[string]string)
+	decision.Approval = nil
+
+	envConfig, exists := e.config.Environments[decision.Environment]
+	if !exists {
+		return nil
+	}
+
+	source := "environments." + decision.Environment
+	trace.change(source+".requires_approval", "requires_approval",
+		decision.RequiresApproval, envConfig.RequiresApproval, "environment setting")
+	decision.RequiresApproval = envConfig.RequiresApproval
+	if envConfig.Approval != nil {
+		decision.Approval = newApproval(envConfig.Approval)
+		trace.change(source+".approval", "requires_approval", decision.RequiresApproval, true,
+			"environment requires "+decision.Approval.String())
+		decision.RequiresApproval = true
+	}
+
+	variables, err := e.renderVariables(decision.Environment, envConfig, branchInfo)
+	if err != nil {
+		return err
+	}
+	for _, k := range sortedKeys(variables) {
+		decision.Variables[k] = variables[k]
+		trace.variable(k, variables[k], source+".variables")
+	}
+
+	// Check if branch is allowed for this environment
+	allowed := e.isBranchAllowed(branchInfo.ShortName, envConfig.AllowedBranches)
+	if trace != nil {
+		matchedBy, _ := e.patterns.MatchList(branchInfo.ShortName, envConfig.AllowedBranches)
+		trace.AllowedBranch = &AllowedTrace{
+			Environment: decision.Environment,
+			Patterns:    envConfig.AllowedBranches,
+			Allowed:     allowed,
+			MatchedBy:   matchedBy,
+		}
+	}
+	if !allowed {
+		decision.addFinding(SeverityError, FindingBranchNotAllowed,
+			fmt.Sprintf("Branch %s may not be allowed to deploy to %s",
+				branchInfo.ShortName, decision.Environment))
+	}
+	return nil
+}
+
 // findBestMapping finds the best matching branch mapping based on priority
 func (e *Engine) findBestMapping(branchName string) *config.BranchMapping {
-	var bestMatch *config.BranchMapping
-	highestPriority := -1
+	if index := e.bestMappingIndex(branchName); index >= 0 {
+		return &e.config.BranchMappings[index]
+	}
+	return nil
+}
+
+// bestMappingIndex returns the index of the best matching branch mapping, or -1.
+// Ties are broken deterministically: higher priority wins, then the more
+// specific pattern (see pattern.Pattern.Specificity), then declaration order.
+func (e *Engine) bestMappingIndex(branchName string) int {
+	best := -1
 
 	for i, mapping := range e.config.BranchMappings {
-		if e.matchesPattern(branchName, mapping.Pattern) {
-			if mapping.Priority > highestPriority {
-				highestPriority = mapping.Priority
-				bestMatch = &e.config.BranchMappings[i]
-			}
+		if !e.matchesPattern(branchName, mapping.Pattern) {
+			continue
+		}
+		if best < 0 || e.outranks(mapping, e.config.BranchMappings[best]) {
+			best = i
 		}
 	}
 
-	return bestMatch
+	return best
 }
 
-// matchesPattern checks if branch name matches a pattern
-func (e *Engine) matchesPattern(branchName, pattern string) bool {
-	// Exact match
-	if branchName == pattern {
-		return true
+// outranks reports whether mapping a takes precedence over an earlier mapping b
+func (e *Engine) outranks(a, b config.BranchMapping) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
 	}
+	return e.specificity(a.Pattern) > e.specificity(b.Pattern)
+}
 
-	// Wildcard pattern (e.g., "feature/*")
-	if strings.HasSuffix(pattern, "/*") {
-		prefix := strings.TrimSuffix(pattern, "/*")
-		return strings.HasPrefix(branchName, prefix+"/")
+// specificity returns the specificity of a compiled pattern
+func (e *Engine) specificity(raw string) int {
+	if p := e.patterns.Get(raw); p != nil {
+		return p.Specificity()
 	}
+	return 0
+}
 
-	// Glob pattern
-	matched, _ := filepath.Match(pattern, branchName)
-	return matched
+// traceMappings records every mapping considered and the winner
+func (e *Engine) traceMappings(trace *Trace, branchName string, winner int) {
+	for i, mapping := range e.config.BranchMappings {
+		entry := MappingTrace{
+			Index:       i,
+			Pattern:     mapping.Pattern,
+			Environment: strings.Join(mapping.Targets(), ", "),
+			Priority:    mapping.Priority,
+			Specificity: e.specificity(mapping.Pattern),
+			Matched:     e.matchesPattern(branchName, mapping.Pattern),
+		}
+		trace.Mappings = append(trace.Mappings, entry)
+		if i == winner {
+			w := entry
+			trace.Winner = &w
+		}
+	}
 }
 
-// shouldDeploy determines if deployment should occur
-func (e *Engine) shouldDeploy(actions []string, branchName string) bool {
+// matchesPattern checks if branch name matches a pattern
+func (e *Engine) matchesPattern(branchName, pattern string) bool {
+	return e.patterns.Match(branchName, pattern)
+}
+
+// shouldDeploy determines if deployment should occur and why
+func (e *Engine) shouldDeploy(actions []string, branchName string) (bool, string) {
 	// Check if "deploy" is in actions
 	for _, action := range actions {
 		if action == "deploy" {
-			return true
+			return true, "mapping includes the deploy action"
 		}
 	}
 
 	// Check auto-deploy branches
-	for _, autoBranch := range e.config.Policies.AutoDeployBranches {
-		if branchName == autoBranch {
-			return true
-		}
+	if autoBranch, auto := e.patterns.MatchList(branchName, e.config.Policies.AutoDeployBranches); auto {
+		return true, "branch matches " + autoBranch + " in policies.auto_deploy_branches"
 	}
 
-	return false
+	return false, "mapping has no deploy action"
 }
 
 // isBranchAllowed checks if branch is allowed for the environment
@@ -136,36 +636,67 @@ func (e *Engine) isBranchAllowed(branchName string, allowedPatterns []string) bo
 		return true
 	}
 
-	for _, pattern := range allowedPatterns {
-		if e.matchesPattern(branchName, pattern) {
-			return true
-		}
-	}
-
-	return false
+	_, allowed := e.patterns.MatchList(branchName, allowedPatterns)
+	return allowed
 }
 
 // applyPolicies applies policy rules to the decision
-func (e *Engine) applyPolicies(decision *Decision, branchInfo *git.BranchInfo) {
+func (e *Engine) applyPolicies(decision *Decision, branchInfo *git.BranchInfo, trace *Trace) {
 	// Check blocked patterns
-	for _, blockedPattern := range e.config.Policies.BlockedBranchPatterns {
-		if e.matchesPattern(branchInfo.ShortName, blockedPattern) {
-			decision.ShouldDeploy = false
-			decision.Warnings = append(decision.Warnings,
+	if blockedPatterns := e.patterns.MatchAll(branchInfo.ShortName, e.config.Policies.BlockedBranchPatterns); blockedPatterns != nil {
+		trace.change("policies.blocked_branch_patterns", "should_deploy", decision.ShouldDeploy, false,
+			"branch matches "+strings.Join(blockedPatterns, ", "))
+		decision.ShouldDeploy = false
+		for _, blockedPattern := range blockedPatterns {
+			decision.addFinding(SeverityError, FindingBlockedBranch,
 				fmt.Sprintf("Branch matches blocked pattern: %s", blockedPattern))
 		}
 	}
 
 	// Add required actions based on policies
 	if e.config.Policies.RequireTests && !contains(decision.Actions, "test") {
-		decision.Actions = append(decision.Actions, "test")
+		actions := append(append([]string{}, decision.Actions...), "test")
+		trace.change("policies.require_tests", "actions", decision.Actions, actions, "tests are required")
+		decision.Actions = actions
 	}
 
+	e.applyCodeReview(decision, branchInfo, trace)
+}
+
+// applyCodeReview requires approval for protected branches when code review is required
+func (e *Engine) applyCodeReview(decision *Decision, branchInfo *git.BranchInfo, trace *Trace) {
 	if e.config.Policies.RequireCodeReview && branchInfo.IsProtected {
+		trace.change("policies.require_code_review", "requires_approval", decision.RequiresApproval, true,
+			"protected branch requires code review")
 		decision.RequiresApproval = true
 	}
 }
 
+// sortedEnvironmentNames returns the names of the configured environments in sorted order
+func sortedEnvironmentNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Environments))
+	for name := range cfg.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// equalTargets checks if two mappings target the same environments
+func equalTargets(a, b []string) bool {
+	return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+}
+
 // contains checks if a slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
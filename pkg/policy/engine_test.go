@@ -1,7 +1,11 @@
 package policy
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
@@ -9,7 +13,10 @@ import (
 
 func TestEvaluate(t *testing.T) {
 	cfg := config.DefaultConfig()
-	engine := NewEngine(cfg)
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
 
 	tests := []struct {
 		name             string
@@ -83,7 +90,10 @@ func TestEvaluate(t *testing.T) {
 }
 
 func TestMatchesPattern(t *testing.T) {
-	engine := NewEngine(config.DefaultConfig())
+	engine, err := NewEngine(config.DefaultConfig())
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
 
 	tests := []struct {
 		name       string
@@ -96,6 +106,19 @@ func TestMatchesPattern(t *testing.T) {
 		{"wildcard no match", "bugfix/auth", "feature/*", false},
 		{"glob match", "release-v1.0", "release-*", true},
 		{"no match", "develop", "main", false},
+		{"nested wildcard match", "feature/team/auth", "feature/*", true},
+		{"trailing /* matches any depth", "users/alice/hotfix-1", "users/*", true},
+		{"glob does not cross slash", "users/a/b-fix", "users/*-fix", false},
+		{"single star segment", "users/alice/hotfix-1", "users/*/hotfix-*", true},
+		{"single star no cross", "users/alice/bob/hotfix-1", "users/*/hotfix-*", false},
+		{"doublestar match", "feature/team/auth", "feature/**", true},
+		{"doublestar matches parent", "feature", "feature/**", true},
+		{"doublestar middle", "users/a/b/hotfix-1", "users/**/hotfix-*", true},
+		{"doublestar middle zero segments", "users/hotfix-1", "users/**/hotfix-*", true},
+		{"regex match", "v1.2.3", `re:v\d+\.\d+\.\d+`, true},
+		{"regex is anchored", "xv1.2.3", `re:v\d+\.\d+\.\d+`, false},
+		{"negation match", "develop", "!main", true},
+		{"negation no match", "main", "!main", false},
 	}
 
 	for _, tt := range tests {
@@ -111,7 +134,10 @@ func TestMatchesPattern(t *testing.T) {
 
 func TestFindBestMapping(t *testing.T) {
 	cfg := config.DefaultConfig()
-	engine := NewEngine(cfg)
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
 
 	tests := []struct {
 		name        string
@@ -138,3 +164,1294 @@ func TestFindBestMapping(t *testing.T) {
 		})
 	}
 }
+
+func TestNewEngineInvalidPatterns(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.BranchMappings = append(cfg.BranchMappings,
+		config.BranchMapping{Pattern: "re:(", Environment: "development"})
+	cfg.Policies.BlockedBranchPatterns = []string{"wip/[abc"}
+
+	_, err := NewEngine(cfg)
+	if err == nil {
+		t.Fatal("Expected error for invalid patterns")
+	}
+	for _, want := range []string{"branch_mappings[8].pattern", "policies.blocked_branch_patterns[0]"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+}
+
+func TestBlockedPatternsWithNegation(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Policies.BlockedBranchPatterns = []string{"release/**", `re:release/1\..*`, "!release/2.*"}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	tests := []struct {
+		branchName     string
+		expectedDeploy bool
+		blockedBy      int // Number of blocked_branch findings, one per matching pattern
+	}{
+		{"release/1.0", false, 2},
+		{"release/3.0", false, 1},
+		{"release/2.0", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.branchName, func(t *testing.T) {
+			decision, err := engine.Evaluate(&git.BranchInfo{
+				ShortName: tt.branchName,
+				Type:      "release",
+				Metadata:  make(map[string]string),
+			})
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			if decision.ShouldDeploy != tt.expectedDeploy {
+				t.Errorf("Expected ShouldDeploy %v, got %v", tt.expectedDeploy, decision.ShouldDeploy)
+			}
+			blockedBy := 0
+			for _, f := range decision.Findings {
+				if f.Code == FindingBlockedBranch {
+					blockedBy++
+				}
+			}
+			if blockedBy != tt.blockedBy {
+				t.Errorf("Expected %d blocked_branch findings, got %d: %v", tt.blockedBy, blockedBy, decision.Findings)
+			}
+		})
+	}
+}
+
+func TestAutoDeployPatterns(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.BranchMappings = []config.BranchMapping{{Pattern: "**", Environment: "development", Actions: []string{"build"}}}
+	cfg.Policies.AutoDeployBranches = []string{"release/**", `re:^hotfix/\d+$`, "!release/legacy-*"}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	tests := []struct {
+		branchName     string
+		expectedDeploy bool
+	}{
+		{"release/1.4/rc", true},
+		{"hotfix/42", true},
+		{"hotfix/login", false},
+		{"release/legacy-1", false},
+		{"main", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.branchName, func(t *testing.T) {
+			decision, err := engine.Evaluate(&git.BranchInfo{ShortName: tt.branchName, Metadata: map[string]string{}})
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			if decision.ShouldDeploy != tt.expectedDeploy {
+				t.Errorf("Expected ShouldDeploy %v, got %v", tt.expectedDeploy, decision.ShouldDeploy)
+			}
+		})
+	}
+
+	cfg.Policies.AutoDeployBranches = []string{"re:("}
+	if _, err := NewEngine(cfg); err == nil || !strings.Contains(err.Error(), "policies.auto_deploy_branches[0]") {
+		t.Errorf("Expected an invalid auto-deploy pattern error, got %v", err)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Policies.BlockedBranchPatterns = []string{"main"}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	decision, err := engine.Explain(&git.BranchInfo{
+		ShortName:   "main",
+		Type:        "main",
+		Metadata:    make(map[string]string),
+		IsProtected: true,
+	})
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+
+	trace := decision.Trace
+	if trace == nil {
+		t.Fatal("Expected a trace")
+	}
+	if len(trace.Mappings) != len(cfg.BranchMappings) {
+		t.Errorf("Expected %d mappings in trace, got %d", len(cfg.BranchMappings), len(trace.Mappings))
+	}
+	if trace.Winner == nil || trace.Winner.Index != 0 {
+		t.Errorf("Expected mapping 0 to win, got %+v", trace.Winner)
+	}
+	if trace.AllowedBranch == nil || !trace.AllowedBranch.Allowed || trace.AllowedBranch.MatchedBy != "main" {
+		t.Errorf("Unexpected allowed-branch trace: %+v", trace.AllowedBranch)
+	}
+
+	sources := make(map[string]string)
+	for _, c := range trace.Changes {
+		sources[c.Source+" "+c.Field] = c.To
+	}
+	expected := map[string]string{
+		"branch_mappings[0] should_deploy":                            "true",
+		"policies.blocked_branch_patterns should_deploy":              "false",
+		"environments.production.requires_approval requires_approval": "true",
+		"policies.require_tests actions":                              "[deploy, notify, test]",
+	}
+	for key, to := range expected {
+		if sources[key] != to {
+			t.Errorf("Expected change %q to %q, got %q", key, to, sources[key])
+		}
+	}
+	// RequireCodeReview did not change anything, so it must not be listed
+	if _, exists := sources["policies.require_code_review requires_approval"]; exists {
+		t.Error("Expected unchanged require_code_review to be omitted")
+	}
+
+	if len(trace.Variables) != 1 || trace.Variables[0].Source != "environments.production.variables" {
+		t.Errorf("Unexpected variable trace: %+v", trace.Variables)
+	}
+
+	// Evaluate does not record a trace
+	decision, _ = engine.Evaluate(&git.BranchInfo{ShortName: "main", Metadata: map[string]string{}})
+	if decision.Trace != nil {
+		t.Error("Expected no trace from Evaluate")
+	}
+}
+
+func TestFindBestMappingTieBreaking(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.BranchMappings = []config.BranchMapping{
+		{Pattern: "feature/*", Environment: "development", Priority: 50},
+		{Pattern: "feature/payments-*", Environment: "staging", Priority: 50},
+		{Pattern: "hotfix/**", Environment: "staging", Priority: 70},
+		{Pattern: "hotfix/*", Environment: "production", Priority: 70},
+		{Pattern: "release/1.0", Environment: "production", Priority: 60},
+		{Pattern: "re:release/.*", Environment: "staging", Priority: 60},
+	}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		branchName  string
+		expectedEnv string
+	}{
+		{"more specific glob wins", "feature/payments-refund", "staging"},
+		{"generic glob still applies", "feature/login", "development"},
+		{"equal specificity keeps declaration order", "hotfix/urgent", "staging"},
+		{"exact name beats regex", "release/1.0", "production"},
+		{"regex applies otherwise", "release/2.0", "staging"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := engine.findBestMapping(tt.branchName)
+			if mapping == nil {
+				t.Fatalf("No mapping found for %s", tt.branchName)
+			}
+			if mapping.Environment != tt.expectedEnv {
+				t.Errorf("Expected environment %s, got %s", tt.expectedEnv, mapping.Environment)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// main and master share priority 100 but never overlap
+	if problems := Validate(config.DefaultConfig()); len(problems) != 0 {
+		t.Errorf("Expected default config to be valid, got %v", problems)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.BranchMappings = append(cfg.BranchMappings,
+		// Same environment as feature/*: not a conflict
+		config.BranchMapping{Pattern: "feature/ui-*", Environment: "development", Priority: 50},
+		// Overlaps feature/* at the same priority with a different environment
+		config.BranchMapping{Pattern: "feature/**", Environment: "staging", Priority: 50},
+		// Different priority: resolved by priority
+		config.BranchMapping{Pattern: "bugfix/**", Environment: "staging", Priority: 60},
+	)
+
+	problems := Validate(cfg)
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %d: %v", len(problems), problems)
+	}
+	for _, problem := range problems {
+		if !strings.Contains(problem, "feature/**") {
+			t.Errorf("Unexpected conflict: %s", problem)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Policies.Rules = []config.Rule{
+		{
+			Name:           "promote-ops-hotfix",
+			When:           `branch.type == "hotfix" && metadata.ticket matches "^OPS-"`,
+			SetEnvironment: "production",
+		},
+		{
+			Name:         "tag-production",
+			When:         `environment == "production"`,
+			SetVariables: map[string]string{"TIER": "critical"},
+			AddActions:   []string{"smoke-test"},
+		},
+		{
+			Name:  "no-wip",
+			When:  `commit.message matches "(?i)^wip"`,
+			Block: true,
+			Warn:  "Work in progress commits are not deployed",
+		},
+	}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	decision, err := engine.Explain(&git.BranchInfo{
+		ShortName:     "hotfix/OPS-7-db",
+		Type:          "hotfix",
+		Metadata:      map[string]string{"ticket": "OPS-7"},
+		CommitMessage: "WIP: retry migrations",
+	})
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+
+	if decision.Environment != "production" {
+		t.Errorf("Expected environment production, got %s", decision.Environment)
+	}
+	if !decision.RequiresApproval {
+		t.Error("Expected production approval to apply after the environment switch")
+	}
+	want := map[string]string{"ENV": "production", "TIER": "critical"}
+	if len(decision.Variables) != len(want) || decision.Variables["ENV"] != "production" || decision.Variables["TIER"] != "critical" {
+		t.Errorf("Expected variables %v, got %v", want, decision.Variables)
+	}
+	if !contains(decision.Actions, "smoke-test") {
+		t.Errorf("Expected smoke-test action, got %v", decision.Actions)
+	}
+	if decision.ShouldDeploy {
+		t.Error("Expected the no-wip rule to block deployment")
+	}
+	if !contains(decision.Warnings, "Deployment blocked by rule no-wip") ||
+		!contains(decision.Warnings, "Work in progress commits are not deployed") {
+		t.Errorf("Expected rule warnings, got %v", decision.Warnings)
+	}
+
+	sources := make(map[string]bool)
+	for _, change := range decision.Trace.Changes {
+		sources[change.Source+" "+change.Field] = true
+	}
+	for _, want := range []string{"policies.rules[0] environment", "policies.rules[1] actions", "policies.rules[2] should_deploy"} {
+		if !sources[want] {
+			t.Errorf("Expected trace change %q, got %+v", want, decision.Trace.Changes)
+		}
+	}
+}
+
+func TestRuleErrors(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Policies.Rules = []config.Rule{
+		{When: `branch.type ==`},
+		{When: ""},
+		{When: "true", SetEnvironment: "qa"},
+	}
+
+	if _, err := NewEngine(cfg); err == nil || !strings.Contains(err.Error(), "policies.rules[0].when") ||
+		!strings.Contains(err.Error(), "policies.rules[1].when") {
+		t.Errorf("Expected compile errors for rules 0 and 1, got %v", err)
+	}
+
+	problems := Validate(cfg)
+	if len(problems) != 3 || !strings.Contains(problems[2], `unknown environment "qa"`) {
+		t.Errorf("Expected 3 problems including the unknown environment, got %v", problems)
+	}
+
+	cfg.Policies.Rules = []config.Rule{{Name: "bad-compare", When: `branch.name > 3`}}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	_, err = engine.Evaluate(&git.BranchInfo{ShortName: "main", Type: "main", Metadata: map[string]string{}})
+	if err == nil || !strings.Contains(err.Error(), "bad-compare") {
+		t.Errorf("Expected evaluation error naming the rule, got %v", err)
+	}
+}
+
+func TestFreezeWindows(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatalf("LoadLocation failed: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.FreezeWindows = []config.FreezeWindow{
+		{
+			Name:           "friday-afternoon",
+			Environments:   []string{"production"},
+			Cron:           "0 14 * * FRI",
+			Duration:       "10h",
+			Timezone:       "Europe/London",
+			ExemptBranches: []string{"hotfix/*"},
+		},
+		{
+			Name:         "month-end",
+			Environments: []string{"production"},
+			Cron:         "0 0 20 * *",
+			Duration:     "240h",
+		},
+		{
+			Name:         "holidays",
+			Environments: []string{"production", "staging"},
+			Start:        "2026-12-24",
+			End:          "2027-01-02",
+			Timezone:     "America/New_York",
+		},
+	}
+	cfg.BranchMappings = append(cfg.BranchMappings,
+		config.BranchMapping{Pattern: "hotfix/urgent-*", Environment: "production", Actions: []string{"deploy"}, Priority: 75})
+
+	tests := []struct {
+		name       string
+		branch     string
+		now        time.Time
+		wantDeploy bool
+		wantWarn   string
+	}{
+		{"before friday window", "main", time.Date(2026, 10, 16, 13, 59, 0, 0, london), true, ""},
+		{"inside friday window", "main", time.Date(2026, 10, 16, 15, 0, 0, 0, london),
+			false, `Deployment freeze "friday-afternoon" is active for production until 2026-10-17 00:00 BST`},
+		{"last minute of friday window", "main", time.Date(2026, 10, 16, 23, 59, 0, 0, london), false, "friday-afternoon"},
+		{"after friday window", "main", time.Date(2026, 10, 17, 0, 0, 0, 0, london), true, ""},
+		{"exempt hotfix", "hotfix/urgent-login", time.Date(2026, 10, 16, 15, 0, 0, 0, london), true, ""},
+		{"other environment", "staging", time.Date(2026, 10, 16, 15, 0, 0, 0, london), true, ""},
+		{"holiday freeze", "staging", time.Date(2026, 12, 25, 12, 0, 0, 0, time.UTC),
+			false, `Deployment freeze "holidays" is active for staging until 2027-01-02 00:00 EST`},
+		{"holiday freeze ended", "staging", time.Date(2027, 1, 2, 5, 0, 0, 0, time.UTC), true, ""},
+		{"long window", "main", time.Date(2026, 10, 29, 12, 0, 0, 0, time.UTC),
+			false, `Deployment freeze "month-end" is active for production until 2026-10-30 00:00 UTC`},
+		{"long window ended", "main", time.Date(2026, 10, 30, 0, 0, 0, 0, time.UTC), true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngine(cfg, WithClock(func() time.Time { return tt.now }))
+			if err != nil {
+				t.Fatalf("NewEngine failed: %v", err)
+			}
+			detector := git.NewDetector(".")
+			decision, err := engine.Evaluate(detector.GetBranchInfo(tt.branch))
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+
+			if decision.ShouldDeploy != tt.wantDeploy {
+				t.Errorf("Expected ShouldDeploy %v, got %v", tt.wantDeploy, decision.ShouldDeploy)
+			}
+			found := tt.wantWarn == ""
+			for _, w := range decision.Warnings {
+				if tt.wantWarn != "" && strings.Contains(w, tt.wantWarn) {
+					found = true
+				}
+				if tt.wantWarn == "" && strings.Contains(w, "freeze") {
+					t.Errorf("Unexpected freeze warning: %s", w)
+				}
+			}
+			if !found {
+				t.Errorf("Expected warning containing %q, got %v", tt.wantWarn, decision.Warnings)
+			}
+		})
+	}
+}
+
+func TestFreezeWindowErrors(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.FreezeWindows = []config.FreezeWindow{
+		{Name: "no-schedule"},
+		{Name: "both", Cron: "0 14 * * FRI", Duration: "1h", Start: "2026-01-01", End: "2026-01-02"},
+		{Name: "bad-cron", Cron: "0 25 * * *", Duration: "1h"},
+		{Name: "bad-zone", Start: "2026-01-01", End: "2026-01-02", Timezone: "Mars/Olympus"},
+		{Name: "reversed", Start: "2026-01-02", End: "2026-01-01"},
+		{Name: "unknown-env", Environments: []string{"qa"}, Start: "2026-01-01", End: "2026-01-02"},
+	}
+
+	if _, err := NewEngine(cfg); err == nil {
+		t.Fatal("Expected errors for invalid freeze windows")
+	}
+
+	problems := Validate(cfg)
+	for i, want := range []string{
+		"freeze_windows[0]: a cron and duration",
+		"freeze_windows[1]: use either",
+		"freeze_windows[2]: invalid cron expression",
+		"freeze_windows[3]: invalid timezone",
+		"freeze_windows[4]: end",
+		`freeze_windows[5].environments: unknown environment "qa"`,
+	} {
+		if i >= len(problems) || !strings.HasPrefix(problems[i], want) {
+			t.Errorf("Expected problem %d to start with %q, got %v", i, want, problems)
+		}
+	}
+}
+
+func TestFindings(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Policies.BlockedBranchPatterns = []string{"wip/*"}
+	cfg.Policies.Rules = []config.Rule{
+		{When: `branch.type == "unknown"`, Warn: "Unknown branch type", Severity: "info"},
+	}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	decision, err := engine.Evaluate(&git.BranchInfo{ShortName: "wip/x", Type: "unknown", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	var got []string
+	for _, f := range decision.Findings {
+		got = append(got, string(f.Severity)+":"+f.Code)
+	}
+	want := []string{"warn:no_mapping", "error:branch_not_allowed", "error:blocked_branch", "info:rule_warning"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected findings %v, got %v", want, got)
+	}
+
+	// Info findings are not listed as warnings
+	if len(decision.Warnings) != 3 || contains(decision.Warnings, "Unknown branch type") {
+		t.Errorf("Expected 3 warnings without the info finding, got %v", decision.Warnings)
+	}
+
+	if f := decision.FirstFinding(SeverityWarn); f == nil || f.Code != FindingBranchNotAllowed {
+		t.Errorf("Expected the first error finding to be preferred, got %+v", f)
+	}
+	if f := decision.FirstFinding(SeverityError); f == nil || f.Code != FindingBranchNotAllowed {
+		t.Errorf("Expected branch_not_allowed, got %+v", f)
+	}
+
+	clean, err := engine.Evaluate(&git.BranchInfo{ShortName: "feature/x", Type: "feature", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if f := clean.FirstFinding(SeverityInfo); f != nil {
+		t.Errorf("Expected no findings, got %+v", f)
+	}
+
+	missing, err := engine.Evaluate(&git.BranchInfo{ShortName: "feature/x", Type: "feature", Metadata: map[string]string{},
+		CommitMissing: "branch feature/x not found in the repository"})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if f := missing.FirstFinding(SeverityWarn); f == nil || f.Code != FindingCommitMissing || !strings.Contains(f.Message, "not found") {
+		t.Errorf("Expected a commit_missing warning, got %+v", f)
+	}
+
+	cfg.Policies.Rules[0].Severity = "fatal"
+	if _, err := NewEngine(cfg); err == nil || !strings.Contains(err.Error(), "policies.rules[0].severity") {
+		t.Errorf("Expected an invalid severity error, got %v", err)
+	}
+}
+
+// fakeHistory is a DeploymentHistory keyed by environment and SHA
+type fakeHistory map[string]bool
+
+func (f fakeHistory) Deployed(environment, sha string) bool {
+	return f[environment+"@"+sha]
+}
+
+func TestPromotion(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Promotion.Chains = []config.PromotionChain{
+		{Name: "release", Environments: []string{"staging", "production"}},
+	}
+	history := fakeHistory{"staging@abc1234": true}
+	main := func(sha string) *git.BranchInfo {
+		return &git.BranchInfo{ShortName: "main", Type: "main", Metadata: map[string]string{}, CommitSHA: sha}
+	}
+
+	tests := []struct {
+		name         string
+		onIneligible string
+		history      DeploymentHistory
+		sha          string
+		wantDeploy   bool
+		wantFinding  string
+	}{
+		{"promoted commit", "warn", history, "abc1234", true, ""},
+		{"ineligible warns", "warn", history, "def5678", true, "warn:promotion_ineligible"},
+		{"ineligible blocks", "block", history, "def5678", false, "error:promotion_ineligible"},
+		{"no history", "block", nil, "def5678", true, "info:promotion_unchecked"},
+		{"no commit", "block", history, "", true, "info:promotion_unchecked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Promotion.Chains[0].OnIneligible = tt.onIneligible
+			var opts []Option
+			if tt.history != nil {
+				opts = append(opts, WithHistory(tt.history))
+			}
+			engine, err := NewEngine(cfg, opts...)
+			if err != nil {
+				t.Fatalf("NewEngine failed: %v", err)
+			}
+
+			decision, err := engine.Evaluate(main(tt.sha))
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			if decision.ShouldDeploy != tt.wantDeploy {
+				t.Errorf("Expected ShouldDeploy %v, got %v", tt.wantDeploy, decision.ShouldDeploy)
+			}
+
+			var got []string
+			for _, f := range decision.Findings {
+				got = append(got, string(f.Severity)+":"+f.Code)
+			}
+			if strings.Join(got, ",") != tt.wantFinding {
+				t.Errorf("Expected findings %q, got %v", tt.wantFinding, got)
+			}
+		})
+	}
+
+	// The first environment of a chain is always eligible
+	engine, err := NewEngine(cfg, WithHistory(fakeHistory{}))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	decision, err := engine.Evaluate(&git.BranchInfo{ShortName: "develop", Type: "develop", Metadata: map[string]string{}, CommitSHA: "def5678"})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(decision.Findings) != 0 {
+		t.Errorf("Expected no findings for staging, got %v", decision.Findings)
+	}
+}
+
+func TestPromotionErrors(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Promotion.Chains = []config.PromotionChain{
+		{Environments: []string{"staging"}},
+		{Environments: []string{"staging", "production"}, OnIneligible: "fail"},
+		{Environments: []string{"qa", "production"}},
+	}
+
+	if _, err := NewEngine(cfg); err == nil {
+		t.Fatal("Expected errors for invalid promotion chains")
+	}
+
+	problems := strings.Join(Validate(cfg), "\n")
+	for _, want := range []string{
+		"promotion.chains[0].environments: a chain needs at least two environments",
+		`promotion.chains[1].on_ineligible: invalid value "fail"`,
+		`promotion.chains[2].environments: unknown environment "qa"`,
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Expected problem %q, got:\n%s", want, problems)
+		}
+	}
+}
+
+func TestMultipleEnvironments(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Environments["perf"] = config.EnvironmentConfig{Name: "perf", Variables: map[string]string{"ENV": "perf"}}
+	cfg.BranchMappings = append(cfg.BranchMappings,
+		config.BranchMapping{Pattern: "release/*", Environments: []string{"staging", "perf"}, Actions: []string{"deploy"}, Priority: 95})
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	decision, err := engine.Explain(&git.BranchInfo{ShortName: "release/1.0", Type: "release", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	targets := decision.Targets()
+	if len(targets) != 2 || targets[0].Environment != "staging" || targets[1].Environment != "perf" {
+		t.Fatalf("Expected staging and perf, got %+v", targets)
+	}
+	if decision.Environment != "staging" || targets[1].Variables["ENV"] != "perf" {
+		t.Errorf("Expected staging as the primary environment with perf variables in its own decision, got %+v", decision)
+	}
+	if decision.Trace.Winner == nil || decision.Trace.Winner.Environment != "staging, perf" {
+		t.Errorf("Expected the trace to show both environments, got %+v", decision.Trace.Winner)
+	}
+
+	// A single-environment decision is its own only target
+	single, err := engine.Evaluate(&git.BranchInfo{ShortName: "main", Type: "main", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(single.Environments) != 0 || len(single.Targets()) != 1 || single.Targets()[0].Environment != "production" {
+		t.Errorf("Expected production as the only target, got %+v", single.Targets())
+	}
+
+	cfg.BranchMappings[len(cfg.BranchMappings)-1].Environment = "staging"
+	cfg.BranchMappings[len(cfg.BranchMappings)-1].Environments = []string{"staging", "qa"}
+	problems := strings.Join(Validate(cfg), "\n")
+	for _, want := range []string{
+		"branch_mappings[8]: use either environment or environments",
+		`branch_mappings[8].environments: unknown environment "qa"`,
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Expected problem %q, got:\n%s", want, problems)
+		}
+	}
+}
+
+func TestApproval(t *testing.T) {
+	cfg := config.DefaultConfig()
+	production := cfg.Environments["production"]
+	production.RequiresApproval = false
+	production.Approval = &config.ApprovalConfig{Teams: []string{"org/platform"}, Users: []string{"alice"}}
+	cfg.Environments["production"] = production
+	cfg.Policies.Rules = []config.Rule{
+		{When: `branch.name == "develop"`, RequireApproval: true},
+	}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	tests := []struct {
+		branch string
+		want   *Approval
+	}{
+		{"main", &Approval{Required: 1, Teams: []string{"org/platform"}, Users: []string{"alice"}}},
+		{"develop", &Approval{Required: 1}},
+		{"feature/x", nil},
+	}
+
+	for _, tt := range tests {
+		decision, err := engine.Evaluate(&git.BranchInfo{ShortName: tt.branch, Metadata: map[string]string{}})
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		if decision.RequiresApproval != (tt.want != nil) {
+			t.Errorf("%s: expected RequiresApproval %v, got %v", tt.branch, tt.want != nil, decision.RequiresApproval)
+		}
+		if tt.want == nil && decision.Approval != nil || tt.want != nil && (decision.Approval == nil ||
+			decision.Approval.String() != tt.want.String()) {
+			t.Errorf("%s: expected approval %v, got %v", tt.branch, tt.want, decision.Approval)
+		}
+	}
+
+	if got, want := tests[0].want.String(), "1 approval from org/platform, alice; self-approval not allowed"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	production.Approval = &config.ApprovalConfig{Required: 3, Users: []string{"alice", "bob"}}
+	cfg.Environments["production"] = production
+	staging := cfg.Environments["staging"]
+	staging.Approval = &config.ApprovalConfig{Required: -1}
+	cfg.Environments["staging"] = staging
+	problems := strings.Join(Validate(cfg), "\n")
+	for _, want := range []string{
+		"environments.production.approval.required: 3 approvals required but only 2 users may approve",
+		"environments.staging.approval.required: must not be negative",
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Expected problem %q, got:\n%s", want, problems)
+		}
+	}
+}
+
+func TestDNSName(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxLength int
+		want      string
+	}{
+		{"preview-user-auth", 63, "preview-user-auth"},
+		{"Preview/User_Auth!!", 63, "preview-user-auth"},
+		{"--feature//x--", 63, "feature-x"},
+		{"preview-" + strings.Repeat("a", 60), 63, "preview-" + strings.Repeat("a", 48) + "-" + sha256Short("preview-"+strings.Repeat("a", 60))},
+		{"preview-abc-defghij", 16, "preview-a-" + sha256Short("preview-abc-defghij")},
+		{"_/_", 63, sha256Short("_/_")},
+	}
+
+	for _, tt := range tests {
+		got := dnsName(tt.name, tt.maxLength)
+		if got != tt.want {
+			t.Errorf("dnsName(%q, %d) = %q, want %q", tt.name, tt.maxLength, got, tt.want)
+		}
+		if len(got) > tt.maxLength {
+			t.Errorf("dnsName(%q, %d) is %d characters long", tt.name, tt.maxLength, len(got))
+		}
+	}
+}
+
+func TestEnvironmentTemplateErrors(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Environments["preview"] = config.EnvironmentConfig{
+		Name:      "preview",
+		Template:  &config.EnvironmentTemplate{Name: "preview-{{ .Slug", MaxLength: 4},
+		Variables: map[string]string{"URL": "{{ .Nope }}"},
+	}
+	cfg.Environments["review"] = config.EnvironmentConfig{Name: "review", Template: &config.EnvironmentTemplate{}}
+	cfg.Environments["demo"] = config.EnvironmentConfig{
+		Name:      "demo",
+		Template:  &config.EnvironmentTemplate{Name: "demo-{{ .Slug }}", URL: "https://{{ .Environment }}.example.com"},
+		Variables: map[string]string{"PREVIEW_URL": "https://demo.example.com"},
+	}
+
+	if _, err := NewEngine(cfg); err == nil {
+		t.Fatal("Expected errors for invalid environment templates")
+	}
+
+	problems := strings.Join(Validate(cfg), "\n")
+	for _, want := range []string{
+		"environments.preview.template.name: template: environments.preview.template.name:1: unclosed action",
+		"environments.preview.template.max_length: must be between 8 and 63",
+		"environments.review.template.name: a name template is required",
+		"environments.demo.variables.PREVIEW_URL: conflicts with environments.demo.template.url",
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Expected problem %q, got:\n%s", want, problems)
+		}
+	}
+
+	// Unknown fields fail when the template is rendered
+	cfg.Environments["preview"] = config.EnvironmentConfig{
+		Name:      "preview",
+		Template:  &config.EnvironmentTemplate{Name: "preview-{{ .Slug }}"},
+		Variables: map[string]string{"URL": "{{ .Nope }}"},
+	}
+	delete(cfg.Environments, "review")
+	delete(cfg.Environments, "demo")
+	cfg.BranchMappings = append(cfg.BranchMappings, config.BranchMapping{Pattern: "preview/*", Environment: "preview", Priority: 60})
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	_, err = engine.Evaluate(&git.BranchInfo{ShortName: "preview/x", Metadata: map[string]string{}})
+	if err == nil || !strings.Contains(err.Error(), "environments.preview.variables.URL") {
+		t.Errorf("Expected a rendering error naming the variable, got %v", err)
+	}
+
+	// A name template that renders nothing has no environment to deploy to
+	cfg.Environments["preview"] = config.EnvironmentConfig{
+		Name:     "preview",
+		Template: &config.EnvironmentTemplate{Name: "{{ .Ticket }}"},
+	}
+	if engine, err = NewEngine(cfg); err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	_, err = engine.Evaluate(&git.BranchInfo{ShortName: "preview/x", Metadata: map[string]string{}})
+	if err == nil || !strings.Contains(err.Error(), "rendered an empty name") {
+		t.Errorf("Expected an empty template name error, got %v", err)
+	}
+}
+
+func TestVariableTemplates(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Environments["staging"] = config.EnvironmentConfig{
+		Name: "staging",
+		Variables: map[string]string{
+			"ENV":     "{{ .Environment }}",
+			"VERSION": `{{ .Version | default "dev" }}`,
+			"TAG":     `{{ var "VERSION" }}-{{ .CommitSHA | truncate 7 }}`,
+			"HOST":    `{{ .Slug | truncate 12 }}.{{ var "DOMAIN" }}`,
+			"DOMAIN":  "example.com",
+			"TICKET":  `{{ .Ticket | lower | default "none" }}`,
+			"ID":      `{{ sha256short .Branch }}`,
+			"NAME":    `{{ slugify .Type }}`,
+		},
+	}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	decision, err := engine.Evaluate(&git.BranchInfo{
+		ShortName: "release/1.4.0",
+		Type:      "release",
+		Metadata:  map[string]string{"suffix": "1.4.0"},
+		CommitSHA: "0123456789abcdef",
+	})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	want := map[string]string{
+		"ENV":     "staging",
+		"VERSION": "1.4.0",
+		"TAG":     "1.4.0-0123456",
+		"HOST":    "release-1-4-.example.com",
+		"DOMAIN":  "example.com",
+		"TICKET":  "none",
+		"ID":      sha256Short("release/1.4.0"),
+		"NAME":    "release",
+	}
+	for k, v := range want {
+		if decision.Variables[k] != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, decision.Variables[k])
+		}
+	}
+
+	tests := []struct {
+		name      string
+		variables map[string]string
+		wantErr   string
+	}{
+		{"cycle", map[string]string{"A": `{{ var "B" }}`, "B": `{{ var "C" }}`, "C": `{{ var "A" }}`},
+			"environment staging: variable A: reference cycle A -> B -> C -> A"},
+		{"self reference", map[string]string{"A": `x{{ var "A" }}`},
+			"environment staging: variable A: reference cycle A -> A"},
+		{"unknown variable", map[string]string{"A": `{{ var "MISSING" }}`},
+			`environment staging: variable A: references unknown variable "MISSING"`},
+		{"unknown field", map[string]string{"A": `{{ .Nope }}`},
+			"environment staging: variable A: template: environments.staging.variables.A:1:3: executing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Environments["staging"] = config.EnvironmentConfig{Name: "staging", Variables: tt.variables}
+			engine, err := NewEngine(cfg)
+			if err != nil {
+				t.Fatalf("NewEngine failed: %v", err)
+			}
+			_, err = engine.Evaluate(&git.BranchInfo{ShortName: "develop", Type: "develop", Metadata: map[string]string{}})
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error starting with %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	cfg.Environments["staging"] = config.EnvironmentConfig{Name: "staging", Variables: map[string]string{"A": "{{ if }}"}}
+	if _, err := NewEngine(cfg); err == nil || !strings.Contains(err.Error(), "environments.staging.variables.A") {
+		t.Errorf("Expected a parse error naming the variable, got %v", err)
+	}
+}
+
+func TestRisk(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Policies.RequireTests = false
+	cfg.Environments["production"] = config.EnvironmentConfig{Name: "production"}
+	cfg.BranchMappings = []config.BranchMapping{
+		{Pattern: "main", Environment: "production", Actions: []string{"deploy"}, Priority: 100},
+		{Pattern: "hotfix/*", Environment: "production", Actions: []string{"deploy"}, Priority: 90},
+	}
+	cfg.Risk = &config.RiskConfig{
+		BranchTypes:    map[string]int{"hotfix": 20},
+		Environments:   map[string]int{"production": 30},
+		Changes:        config.RiskChanges{PerFile: 1, PerHundredLines: 10, Max: 15},
+		SensitivePaths: []config.RiskPath{{Pattern: "migrations/**", Weight: 25}},
+		OffHours:       &config.RiskOffHours{Start: 18, End: 8, Weekends: true, Timezone: "Europe/London", Weight: 10},
+		Thresholds: []config.RiskThreshold{
+			{Name: "high", Score: 70, RequireApproval: true},
+			{Name: "medium", Score: 40, AddActions: []string{"security-scan"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		branch   git.BranchInfo
+		now      string
+		score    int
+		level    string
+		approval bool
+		actions  []string
+	}{
+		{
+			name:   "small change in working hours",
+			branch: git.BranchInfo{ShortName: "main", Type: "main", ChangedFiles: []string{"README.md"}, LinesChanged: 5},
+			now:    "2024-06-12T10:00:00Z", // Wednesday, 11:00 in London
+			score:  31,
+		},
+		{
+			name:    "change after hours",
+			branch:  git.BranchInfo{ShortName: "main", Type: "main", ChangedFiles: []string{"README.md"}, LinesChanged: 5},
+			now:     "2024-06-12T17:30:00Z", // 18:30 in London
+			score:   41,
+			level:   "medium",
+			actions: []string{"deploy", "security-scan"},
+		},
+		{
+			name: "hotfix touching migrations at the weekend",
+			branch: git.BranchInfo{ShortName: "hotfix/db", Type: "hotfix",
+				ChangedFiles: []string{"migrations/002.sql", "src/db.go"}, LinesChanged: 400},
+			now:      "2024-06-15T12:00:00Z", // Saturday
+			score:    100,
+			level:    "high",
+			approval: true,
+			actions:  []string{"deploy", "security-scan"},
+		},
+	}
+
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.now)
+		engine, err := NewEngine(cfg, WithClock(func() time.Time { return now }))
+		if err != nil {
+			t.Fatalf("NewEngine failed: %v", err)
+		}
+		tt.branch.Metadata = map[string]string{}
+		decision, err := engine.Evaluate(&tt.branch)
+		if err != nil {
+			t.Fatalf("%s: Evaluate failed: %v", tt.name, err)
+		}
+		if decision.Risk == nil {
+			t.Fatalf("%s: expected a risk score", tt.name)
+		}
+		if decision.Risk.Score != tt.score || decision.Risk.Level != tt.level {
+			t.Errorf("%s: expected risk %d (%s), got %s: %+v", tt.name, tt.score, tt.level, decision.Risk, decision.Risk.Factors)
+		}
+		if decision.RequiresApproval != tt.approval {
+			t.Errorf("%s: expected RequiresApproval %v, got %v", tt.name, tt.approval, decision.RequiresApproval)
+		}
+		if tt.actions != nil && strings.Join(decision.Actions, ",") != strings.Join(tt.actions, ",") {
+			t.Errorf("%s: expected actions %v, got %v", tt.name, tt.actions, decision.Actions)
+		}
+	}
+
+	cfg.Risk = &config.RiskConfig{
+		Environments:   map[string]int{"prod": 10},
+		SensitivePaths: []config.RiskPath{{Pattern: "re:(", Weight: 5}},
+		OffHours:       &config.RiskOffHours{Start: 24, End: 8, Timezone: "Mars/Olympus"},
+	}
+	if _, err := NewEngine(cfg); err == nil {
+		t.Error("Expected invalid risk settings to be rejected")
+	}
+	problems := strings.Join(Validate(cfg), "\n")
+	for _, want := range []string{
+		"risk.sensitive_paths[0].pattern",
+		"risk.off_hours.start: hour 24 out of range 0-23",
+		`risk.off_hours.timezone: invalid timezone "Mars/Olympus"`,
+		`risk.environments: unknown environment "prod"`,
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Expected problem %q, got:\n%s", want, problems)
+		}
+	}
+}
+
+func TestActionCatalog(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Actions = map[string]config.ActionConfig{
+		"build":  {},
+		"test":   {Needs: []string{"build"}},
+		"deploy": {Needs: []string{"test"}},
+		"notify": {Needs: []string{"deploy"}},
+	}
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	// main maps to [deploy, notify] and the test policy appends test
+	decision, err := engine.Explain(&git.BranchInfo{ShortName: "main", Metadata: map[string]string{}})
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if got := strings.Join(decision.Actions, ","); got != "build,test,deploy,notify" {
+		t.Errorf("Expected ordered actions, got %s", got)
+	}
+	if len(decision.Plan) != 4 {
+		t.Errorf("Expected 4 stages, got %+v", decision.Plan)
+	}
+	var traced bool
+	for _, c := range decision.Trace.Changes {
+		traced = traced || c.Source == "actions"
+	}
+	if !traced {
+		t.Error("Expected the ordering in the trace")
+	}
+
+	cfg.Actions["build"] = config.ActionConfig{Needs: []string{"deploy", "package"}}
+	cfg.Policies.Rules = []config.Rule{{When: `branch.type == "hotfix"`, AddActions: []string{"security-scan"}}}
+	if _, err := NewEngine(cfg); err == nil {
+		t.Error("Expected a dependency cycle to be rejected")
+	}
+	problems := strings.Join(Validate(cfg), "\n")
+	for _, want := range []string{
+		"actions.build.needs: dependency cycle build -> deploy -> test -> build",
+		`actions.build.needs: unknown action "package"`,
+		`policies.rules[0].add_actions: unknown action "security-scan"`,
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Expected problem %q, got:\n%s", want, problems)
+		}
+	}
+}
+
+func TestPlugins(t *testing.T) {
+	dir := t.TempDir()
+	script := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	catalog := script("catalog", `input=$(cat)
+case "$input" in
+  *'"short_name":"main"'*'"environment":"production"'*)
+    echo '{"warnings": ["service has no owner"], "add_actions": ["smoke-test"], "set_variables": {"OWNER": "unknown"}}' ;;
+  *) echo '{"block": true, "reason": "not in the service catalog"}' ;;
+esac`)
+	slow := script("slow", "exec sleep 5")
+	broken := script("broken", "echo 'catalog unavailable' >&2; exit 3")
+
+	evaluate := func(branch string, cfgs ...config.PluginConfig) *Decision {
+		t.Helper()
+		plugins, err := NewPlugins(cfgs)
+		if err != nil {
+			t.Fatalf("NewPlugins failed: %v", err)
+		}
+		engine, err := NewEngine(config.DefaultConfig(), WithPlugins(plugins))
+		if err != nil {
+			t.Fatalf("NewEngine failed: %v", err)
+		}
+		decision, err := engine.Evaluate(&git.BranchInfo{ShortName: branch, Metadata: map[string]string{}})
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		return decision
+	}
+
+	d := evaluate("main", config.PluginConfig{Name: "catalog", Command: catalog})
+	if !d.ShouldDeploy || d.Variables["OWNER"] != "unknown" || !contains(d.Actions, "smoke-test") ||
+		!containsFinding(d.Findings, Finding{Severity: SeverityWarn, Code: FindingPluginWarning, Message: "service has no owner"}) {
+		t.Errorf("Expected the plugin patch to be applied, got %+v", d)
+	}
+
+	d = evaluate("staging", config.PluginConfig{Name: "catalog", Command: catalog})
+	if d.ShouldDeploy || !containsFinding(d.Findings, Finding{Severity: SeverityError, Code: FindingPluginBlocked, Message: "not in the service catalog"}) {
+		t.Errorf("Expected the plugin to block the deployment, got %+v", d.Findings)
+	}
+
+	d = evaluate("main", config.PluginConfig{Name: "slow", Command: slow, Timeout: "100ms"})
+	if d.ShouldDeploy || d.Findings[0].Message != "Plugin slow failed: timed out after 100ms" {
+		t.Errorf("Expected a timed out plugin to fail closed, got %v %+v", d.ShouldDeploy, d.Findings)
+	}
+
+	d = evaluate("main", config.PluginConfig{Command: broken, OnFailure: "open"})
+	if !d.ShouldDeploy || len(d.Findings) != 1 || d.Findings[0].Severity != SeverityWarn ||
+		!strings.Contains(d.Findings[0].Message, "exit status 3: catalog unavailable") {
+		t.Errorf("Expected a failing plugin to fail open, got %v %+v", d.ShouldDeploy, d.Findings)
+	}
+
+	_, err := NewPlugins([]config.PluginConfig{{Timeout: "soon", OnFailure: "maybe"}})
+	for _, want := range []string{
+		"plugins[0].command: a command is required",
+		`plugins[0].timeout: invalid duration "soon"`,
+		`plugins[0].on_failure: invalid value "maybe"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q, got %v", want, err)
+		}
+	}
+}
+
+func TestRego(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.rego")
+	policy := `package branchci
+
+# Release branches deploy to production with approval and an extra action
+decision := {
+	"environment": "production",
+	"requires_approval": true,
+	"actions": array.concat(input.default_decision.actions, ["change-ticket"]),
+	"variables": {"TICKET_REQUIRED": "true"},
+} if input.branch.type == "release"
+
+deny contains msg if {
+	input.default_decision.environment == "production"
+	not input.branch.commit_sha
+	msg := "production deployments need a commit"
+}
+
+warn contains "no environments configured" if count(input.config.environments) == 0
+`
+	if err := os.WriteFile(path, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	regoPolicy, err := LoadRego(path)
+	if err != nil {
+		t.Fatalf("LoadRego failed: %v", err)
+	}
+	engine, err := NewEngine(config.DefaultConfig(), WithRego(regoPolicy))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	evaluate := func(info *git.BranchInfo) *Decision {
+		t.Helper()
+		info.Metadata = map[string]string{}
+		decision, err := engine.Explain(info)
+		if err != nil {
+			t.Fatalf("Explain failed: %v", err)
+		}
+		return decision
+	}
+
+	d := evaluate(&git.BranchInfo{ShortName: "release/1.2", Type: "release", CommitSHA: "abc123"})
+	if d.Environment != "production" || !d.RequiresApproval || !contains(d.Actions, "change-ticket") ||
+		d.Variables["TICKET_REQUIRED"] != "true" {
+		t.Errorf("Expected the Rego decision to be applied, got %+v", d)
+	}
+	if len(d.Actions) < 2 {
+		t.Errorf("Expected the default actions to be kept, got %v", d.Actions)
+	}
+	// The settings of the new environment replace those of the mapped one
+	if d.Variables["ENV"] != "production" ||
+		!containsFinding(d.Findings, Finding{Severity: SeverityError, Code: FindingBranchNotAllowed, Message: "Branch release/1.2 may not be allowed to deploy to production"}) ||
+		containsFinding(d.Findings, Finding{Severity: SeverityError, Code: FindingBranchNotAllowed, Message: "Branch release/1.2 may not be allowed to deploy to staging"}) {
+		t.Errorf("Expected the production settings, got %v %+v", d.Variables, d.Findings)
+	}
+	traced := false
+	for _, change := range d.Trace.Changes {
+		traced = traced || (change.Source == "rego" && change.Field == "environment" && change.To == "production")
+	}
+	if !traced {
+		t.Errorf("Expected the trace to record the Rego change, got %+v", d.Trace.Changes)
+	}
+
+	d = evaluate(&git.BranchInfo{ShortName: "main", Type: "main"})
+	if d.ShouldDeploy || d.Environment != "production" ||
+		!containsFinding(d.Findings, Finding{Severity: SeverityError, Code: FindingRegoDenied, Message: "production deployments need a commit"}) {
+		t.Errorf("Expected the Rego policy to deny the deployment, got %v %+v", d.ShouldDeploy, d.Findings)
+	}
+
+	d = evaluate(&git.BranchInfo{ShortName: "develop", Type: "develop"})
+	if !d.ShouldDeploy || d.Environment != "staging" || len(d.Findings) != 0 {
+		t.Errorf("Expected the default decision when the policy defines nothing, got %+v", d)
+	}
+
+	if _, err := LoadRego(filepath.Join(dir, "missing.rego")); err == nil {
+		t.Error("Expected an error for a missing policy")
+	}
+
+	// The policy applies to every environment of a multi-environment mapping
+	multiPath := filepath.Join(dir, "multi.rego")
+	multiPolicy := `package branchci
+
+decision := {"requires_approval": false} if startswith(input.branch.short_name, "perf/")
+
+deny contains "perf branches may not deploy" if startswith(input.branch.short_name, "perf/")
+`
+	if err := os.WriteFile(multiPath, []byte(multiPolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	multiRego, err := LoadRego(multiPath)
+	if err != nil {
+		t.Fatalf("LoadRego failed: %v", err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Environments["perf"] = config.EnvironmentConfig{
+		Name:             "perf",
+		RequiresApproval: true,
+		AllowedBranches:  []string{"perf/*"},
+		Variables:        map[string]string{"ENV": "perf"},
+	}
+	cfg.BranchMappings = append(cfg.BranchMappings, config.BranchMapping{
+		Pattern: "perf/*", Environments: []string{"production", "perf"}, Actions: []string{"deploy"}, Priority: 60,
+	})
+	engine, err = NewEngine(cfg, WithRego(multiRego))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	d = evaluate(&git.BranchInfo{ShortName: "perf/load", Type: "feature"})
+	if d.ShouldDeploy || d.RequiresApproval || d.Approval != nil || len(d.Environments) != 2 {
+		t.Errorf("Expected a denied deployment without approval, got %+v", d)
+	}
+	for _, env := range d.Environments {
+		if env.ShouldDeploy || env.RequiresApproval || env.Approval != nil {
+			t.Errorf("Expected %s to be denied without approval, got %+v", env.Environment, env)
+		}
+	}
+
+	// A new environment keeps the approval required by code review and
+	// rules, and new actions are planned with the action catalog
+	movePath := filepath.Join(dir, "move.rego")
+	movePolicy := `package branchci
+
+decision := {"environment": "staging", "actions": ["deploy"]}
+`
+	if err := os.WriteFile(movePath, []byte(movePolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	moveRego, err := LoadRego(movePath)
+	if err != nil {
+		t.Fatalf("LoadRego failed: %v", err)
+	}
+	cfg = config.DefaultConfig()
+	cfg.Actions = map[string]config.ActionConfig{
+		"build":  {},
+		"deploy": {Needs: []string{"build"}},
+	}
+	cfg.Policies.Rules = []config.Rule{{Name: "feature-approval", When: `branch.type == "feature"`, RequireApproval: true}}
+	engine, err = NewEngine(cfg, WithRego(moveRego))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	d = evaluate(&git.BranchInfo{ShortName: "main", Type: "main", IsProtected: true})
+	if d.Environment != "staging" || d.Variables["ENV"] != "staging" || !d.RequiresApproval || d.Approval == nil {
+		t.Errorf("Expected staging with code review approval, got %+v", d)
+	}
+	if strings.Join(d.Actions, ",") != "build,deploy" || len(d.Plan) != 2 ||
+		d.Plan[1].Actions[0].Name != "deploy" || strings.Join(d.Plan[1].Actions[0].Needs, ",") != "build" {
+		t.Errorf("Expected deploy to be planned after build, got %v %+v", d.Actions, d.Plan)
+	}
+
+	d = evaluate(&git.BranchInfo{ShortName: "feature/login", Type: "feature"})
+	if d.Environment != "staging" || !d.RequiresApproval {
+		t.Errorf("Expected the rule's approval to be kept, got %+v", d)
+	}
+}
+
+func TestNeedsLineStats(t *testing.T) {
+	needs := func(cfg *config.Config, opts ...Option) bool {
+		t.Helper()
+		engine, err := NewEngine(cfg, opts...)
+		if err != nil {
+			t.Fatalf("NewEngine failed: %v", err)
+		}
+		return engine.NeedsLineStats()
+	}
+
+	cfg := config.DefaultConfig()
+	if needs(cfg) {
+		t.Error("Expected the default config not to need line stats")
+	}
+
+	cfg.Risk = &config.RiskConfig{Changes: config.RiskChanges{PerFile: 2}}
+	cfg.Policies.Rules = []config.Rule{{When: "commit.files_changed > 10", Block: true}}
+	if needs(cfg) {
+		t.Error("Expected file counts alone not to need line stats")
+	}
+
+	cfg.Policies.Rules = append(cfg.Policies.Rules, config.Rule{When: "commit.lines_changed > 500", Block: true})
+	if !needs(cfg) {
+		t.Error("Expected a rule reading lines_changed to need line stats")
+	}
+
+	cfg = config.DefaultConfig()
+	cfg.Risk = &config.RiskConfig{Changes: config.RiskChanges{PerHundredLines: 5}}
+	if !needs(cfg) {
+		t.Error("Expected risk.changes.per_hundred_lines to need line stats")
+	}
+
+	if !needs(config.DefaultConfig(), WithPlugins([]*Plugin{{}})) {
+		t.Error("Expected plugins to need line stats")
+	}
+}
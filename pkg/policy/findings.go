@@ -0,0 +1,107 @@
+package policy
+
+import "fmt"
+
+// Severity ranks how serious a finding is
+type Severity string
+
+const (
+	SeverityInfo  Severity = "info"
+	SeverityWarn  Severity = "warn"
+	SeverityError Severity = "error"
+)
+
+// severityRanks orders severities from least to most serious
+var severityRanks = map[Severity]int{
+	SeverityInfo:  1,
+	SeverityWarn:  2,
+	SeverityError: 3,
+}
+
+// ParseSeverity parses a severity name
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(s)
+	if _, ok := severityRanks[severity]; !ok {
+		return "", fmt.Errorf("invalid severity %q (expected info, warn or error)", s)
+	}
+	return severity, nil
+}
+
+// AtLeast reports whether s is at least as serious as other
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// Finding codes identify what produced a finding
+const (
+	FindingNoMapping           = "no_mapping"
+	FindingBranchNotAllowed    = "branch_not_allowed"
+	FindingBlockedBranch       = "blocked_branch"
+	FindingFreezeWindow        = "freeze_window"
+	FindingFreezeExempt        = "freeze_exempt"
+	FindingRuleBlocked         = "rule_blocked"
+	FindingRuleWarning         = "rule_warning"
+	FindingPromotionIneligible = "promotion_ineligible"
+	FindingPromotionUnchecked  = "promotion_unchecked"
+	FindingBranchDeleted       = "branch_deleted"
+	FindingCommitMissing       = "commit_missing"
+	FindingRiskThreshold       = "risk_threshold"
+	FindingUnknownAction       = "unknown_action"
+	FindingPluginWarning       = "plugin_warning"
+	FindingPluginBlocked       = "plugin_blocked"
+	FindingPluginFailed        = "plugin_failed"
+	FindingRegoWarning         = "rego_warning"
+	FindingRegoDenied          = "rego_denied"
+)
+
+// Finding is a message about a decision with a severity and a stable code
+type Finding struct {
+	Severity Severity `json:"severity" yaml:"severity"`
+	Code     string   `json:"code" yaml:"code"`
+	Message  string   `json:"message" yaml:"message"`
+}
+
+// addFinding records a finding. Warn and error findings are also listed in
+// Warnings so that consumers of the plain messages keep working.
+func (d *Decision) addFinding(severity Severity, code, message string) {
+	d.Findings = append(d.Findings, Finding{Severity: severity, Code: code, Message: message})
+	if severity.AtLeast(SeverityWarn) {
+		d.Warnings = append(d.Warnings, message)
+	}
+}
+
+// removeFindings removes the findings with the given code, and their warnings
+func (d *Decision) removeFindings(code string) {
+	var findings []Finding
+	for _, f := range d.Findings {
+		if f.Code != code {
+			findings = append(findings, f)
+			continue
+		}
+		if f.Severity.AtLeast(SeverityWarn) {
+			for i, warning := range d.Warnings {
+				if warning == f.Message {
+					d.Warnings = append(d.Warnings[:i], d.Warnings[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	d.Findings = findings
+}
+
+// FirstFinding returns the first finding at or above min, preferring error
+// findings over less serious ones, or nil if there is none
+func (d *Decision) FirstFinding(min Severity) *Finding {
+	var first *Finding
+	for i := range d.Findings {
+		f := &d.Findings[i]
+		if !f.Severity.AtLeast(min) {
+			continue
+		}
+		if first == nil || severityRanks[f.Severity] > severityRanks[first.Severity] {
+			first = f
+		}
+	}
+	return first
+}
@@ -0,0 +1,166 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	// Embed the time zone database so freeze window time zones resolve in
+	// minimal containers without one
+	_ "time/tzdata"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/schedule"
+)
+
+// freezeTimeLayouts lists the accepted formats for absolute window bounds
+var freezeTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// freezeWindow is a compiled freeze window
+type freezeWindow struct {
+	config.FreezeWindow
+	location *time.Location
+	cron     *schedule.Cron
+	duration time.Duration
+	start    time.Time
+	end      time.Time
+}
+
+// compileFreezeWindows parses the schedule of every freeze window
+func compileFreezeWindows(cfg *config.Config) ([]*freezeWindow, []error) {
+	windows := make([]*freezeWindow, len(cfg.FreezeWindows))
+	var errs []error
+
+	for i, fw := range cfg.FreezeWindows {
+		w, err := compileFreezeWindow(fw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("freeze_windows[%d]: %w", i, err))
+			continue
+		}
+		windows[i] = w
+	}
+
+	return windows, errs
+}
+
+// compileFreezeWindow parses a single freeze window
+func compileFreezeWindow(fw config.FreezeWindow) (*freezeWindow, error) {
+	w := &freezeWindow{FreezeWindow: fw, location: time.UTC}
+
+	if fw.Timezone != "" {
+		loc, err := time.LoadLocation(fw.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", fw.Timezone, err)
+		}
+		w.location = loc
+	}
+
+	recurring := fw.Cron != "" || fw.Duration != ""
+	absolute := fw.Start != "" || fw.End != ""
+	switch {
+	case recurring && absolute:
+		return nil, errors.New("use either cron and duration or start and end, not both")
+
+	case recurring:
+		if fw.Cron == "" || fw.Duration == "" {
+			return nil, errors.New("recurring windows need both cron and duration")
+		}
+		cron, err := schedule.ParseCron(fw.Cron)
+		if err != nil {
+			return nil, err
+		}
+		duration, err := time.ParseDuration(fw.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q: %w", fw.Duration, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("duration %q must be positive", fw.Duration)
+		}
+		w.cron, w.duration = cron, duration
+
+	case absolute:
+		if fw.Start == "" || fw.End == "" {
+			return nil, errors.New("absolute windows need both start and end")
+		}
+		var err error
+		if w.start, err = parseFreezeTime(fw.Start, w.location); err != nil {
+			return nil, err
+		}
+		if w.end, err = parseFreezeTime(fw.End, w.location); err != nil {
+			return nil, err
+		}
+		if !w.end.After(w.start) {
+			return nil, fmt.Errorf("end %q must be after start %q", fw.End, fw.Start)
+		}
+
+	default:
+		return nil, errors.New("a cron and duration or a start and end is required")
+	}
+
+	return w, nil
+}
+
+// parseFreezeTime parses an absolute window bound in loc
+func parseFreezeTime(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range freezeTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (expected RFC 3339, YYYY-MM-DDTHH:MM or YYYY-MM-DD)", value)
+}
+
+// activeUntil reports whether the window covers now and when it ends
+func (w *freezeWindow) activeUntil(now time.Time) (time.Time, bool) {
+	if w.cron == nil {
+		return w.end, !now.Before(w.start) && now.Before(w.end)
+	}
+
+	// The window is active when it started within the last duration; the most
+	// recent start decides when it ends
+	local := now.In(w.location)
+	var start time.Time
+	for t := w.cron.Next(local.Add(-w.duration)); !t.IsZero() && !t.After(local); t = w.cron.Next(t) {
+		start = t
+	}
+	if start.IsZero() {
+		return time.Time{}, false
+	}
+	return start.Add(w.duration), true
+}
+
+// appliesTo reports whether the window covers the environment
+func (w *freezeWindow) appliesTo(environment string) bool {
+	return len(w.Environments) == 0 || contains(w.Environments, environment)
+}
+
+// applyFreezeWindows disables deployment when an active freeze window covers
+// the decision's environment and the branch is not exempt
+func (e *Engine) applyFreezeWindows(decision *Decision, branchInfo *git.BranchInfo, now time.Time, trace *Trace) {
+	for i, w := range e.freezeWindows {
+		if !w.appliesTo(decision.Environment) {
+			continue
+		}
+		until, active := w.activeUntil(now)
+		if !active {
+			continue
+		}
+		if exemptBy, exempt := e.patterns.MatchList(branchInfo.ShortName, w.ExemptBranches); exempt {
+			decision.addFinding(SeverityInfo, FindingFreezeExempt,
+				fmt.Sprintf("Branch %s is exempt from deployment freeze %q via %s", branchInfo.ShortName, w.Name, exemptBy))
+			continue
+		}
+
+		message := fmt.Sprintf("Deployment freeze %q is active for %s until %s",
+			w.Name, decision.Environment, until.In(w.location).Format("2006-01-02 15:04 MST"))
+		trace.change(fmt.Sprintf("freeze_windows[%d]", i), "should_deploy", decision.ShouldDeploy, false, message)
+		decision.ShouldDeploy = false
+		decision.addFinding(SeverityError, FindingFreezeWindow, message)
+	}
+}
@@ -0,0 +1,238 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+)
+
+// defaultPluginTimeout bounds a plugin run when no timeout is configured
+const defaultPluginTimeout = 10 * time.Second
+
+// Plugin is an external policy executable. It reads a PluginInput as JSON
+// on stdin and writes a PluginPatch as JSON on stdout.
+type Plugin struct {
+	config.PluginConfig
+	timeout    time.Duration
+	failClosed bool
+}
+
+// PluginInput is what a plugin receives on stdin
+type PluginInput struct {
+	Branch   PluginBranch `json:"branch"`
+	Decision *Decision    `json:"decision"`
+}
+
+// PluginBranch is the branch information passed to plugins
+type PluginBranch struct {
+	Name          string            `json:"name"`
+	ShortName     string            `json:"short_name"`
+	Type          string            `json:"type"`
+	Metadata      map[string]string `json:"metadata"`
+	IsProtected   bool              `json:"is_protected"`
+	CommitSHA     string            `json:"commit_sha,omitempty"`
+	CommitMessage string            `json:"commit_message,omitempty"`
+	CommitAuthor  string            `json:"commit_author,omitempty"`
+	TargetBranch  string            `json:"target_branch,omitempty"`
+	ChangedFiles  []string          `json:"changed_files,omitempty"`
+	LinesChanged  int               `json:"lines_changed,omitempty"`
+	Deleted       bool              `json:"deleted,omitempty"`
+}
+
+// PluginPatch is the change a plugin makes to the decision. Empty output
+// leaves the decision unchanged.
+type PluginPatch struct {
+	Warnings        []string          `json:"warnings,omitempty"`
+	AddActions      []string          `json:"add_actions,omitempty"`
+	RemoveActions   []string          `json:"remove_actions,omitempty"`
+	SetVariables    map[string]string `json:"set_variables,omitempty"`
+	RequireApproval bool              `json:"require_approval,omitempty"`
+	Block           bool              `json:"block,omitempty"`
+	Reason          string            `json:"reason,omitempty"` // Why the deployment was blocked
+}
+
+// WithPlugins sets the plugins run on every decision
+func WithPlugins(plugins []*Plugin) Option {
+	return func(e *Engine) {
+		e.plugins = plugins
+	}
+}
+
+// NewPlugins checks plugin settings and returns the plugins to pass to WithPlugins
+func NewPlugins(cfgs []config.PluginConfig) ([]*Plugin, error) {
+	plugins, errs := compilePlugins(cfgs)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return plugins, nil
+}
+
+// compilePlugins checks the settings of every plugin
+func compilePlugins(cfgs []config.PluginConfig) ([]*Plugin, []error) {
+	plugins := make([]*Plugin, 0, len(cfgs))
+	var errs []error
+
+	for i, cfg := range cfgs {
+		p := &Plugin{PluginConfig: cfg, timeout: defaultPluginTimeout, failClosed: true}
+		if cfg.Command == "" {
+			errs = append(errs, fmt.Errorf("plugins[%d].command: a command is required", i))
+		}
+		if cfg.Timeout != "" {
+			timeout, err := time.ParseDuration(cfg.Timeout)
+			if err != nil || timeout <= 0 {
+				errs = append(errs, fmt.Errorf("plugins[%d].timeout: invalid duration %q", i, cfg.Timeout))
+			}
+			p.timeout = timeout
+		}
+		switch cfg.OnFailure {
+		case "", "closed":
+		case "open":
+			p.failClosed = false
+		default:
+			errs = append(errs, fmt.Errorf("plugins[%d].on_failure: invalid value %q (expected open or closed)", i, cfg.OnFailure))
+		}
+		plugins = append(plugins, p)
+	}
+
+	return plugins, errs
+}
+
+// label returns the plugin's name, or its command when unnamed
+func (p *Plugin) label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Command
+}
+
+// run executes the plugin with input on stdin and decodes its patch
+func (p *Plugin) run(input []byte) (*PluginPatch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", p.timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	patch := &PluginPatch{}
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return patch, nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), patch); err != nil {
+		return nil, fmt.Errorf("invalid output: %w", err)
+	}
+	return patch, nil
+}
+
+// applyPlugins runs every plugin on the draft decision in order and applies
+// its patch. A plugin that fails blocks the deployment when it fails closed
+// and only warns when it fails open.
+func (e *Engine) applyPlugins(decision *Decision, branchInfo *git.BranchInfo, trace *Trace) {
+	for i, plugin := range e.plugins {
+		source := fmt.Sprintf("plugins[%d]", i)
+
+		input, err := json.Marshal(PluginInput{Branch: pluginBranch(branchInfo), Decision: decision})
+		var patch *PluginPatch
+		if err == nil {
+			patch, err = plugin.run(input)
+		}
+		if err != nil {
+			message := fmt.Sprintf("Plugin %s failed: %v", plugin.label(), err)
+			if !plugin.failClosed {
+				decision.addFinding(SeverityWarn, FindingPluginFailed, message)
+				continue
+			}
+			trace.change(source, "should_deploy", decision.ShouldDeploy, false, "plugin failed closed")
+			decision.ShouldDeploy = false
+			decision.addFinding(SeverityError, FindingPluginFailed, message)
+			continue
+		}
+
+		reason := "plugin " + plugin.label()
+		if len(patch.AddActions) > 0 || len(patch.RemoveActions) > 0 {
+			actions := make([]string, 0, len(decision.Actions)+len(patch.AddActions))
+			for _, action := range decision.Actions {
+				if !contains(patch.RemoveActions, action) {
+					actions = append(actions, action)
+				}
+			}
+			for _, action := range patch.AddActions {
+				if !contains(actions, action) {
+					actions = append(actions, action)
+				}
+			}
+			trace.change(source, "actions", decision.Actions, actions, reason)
+			decision.Actions = actions
+
+			// Removing the deploy action disables deployment
+			if contains(patch.RemoveActions, "deploy") && decision.ShouldDeploy {
+				trace.change(source, "should_deploy", true, false, reason)
+				decision.ShouldDeploy = false
+			}
+		}
+
+		if patch.RequireApproval {
+			trace.change(source, "requires_approval", decision.RequiresApproval, true, reason)
+			decision.RequiresApproval = true
+			decision.approvalRaised = true
+		}
+
+		for _, k := range sortedKeys(patch.SetVariables) {
+			decision.Variables[k] = patch.SetVariables[k]
+			trace.variable(k, patch.SetVariables[k], source)
+		}
+
+		for _, warning := range patch.Warnings {
+			decision.addFinding(SeverityWarn, FindingPluginWarning, warning)
+		}
+
+		if patch.Block {
+			message := patch.Reason
+			if message == "" {
+				message = fmt.Sprintf("Deployment blocked by plugin %s", plugin.label())
+			}
+			trace.change(source, "should_deploy", decision.ShouldDeploy, false, reason)
+			decision.ShouldDeploy = false
+			decision.addFinding(SeverityError, FindingPluginBlocked, message)
+		}
+	}
+}
+
+// pluginBranch converts branch information into the plugin input type
+func pluginBranch(b *git.BranchInfo) PluginBranch {
+	return PluginBranch{
+		Name:          b.Name,
+		ShortName:     b.ShortName,
+		Type:          b.Type,
+		Metadata:      b.Metadata,
+		IsProtected:   b.IsProtected,
+		CommitSHA:     b.CommitSHA,
+		CommitMessage: b.CommitMessage,
+		CommitAuthor:  b.CommitAuthor,
+		TargetBranch:  b.TargetBranch,
+		ChangedFiles:  b.ChangedFiles,
+		LinesChanged:  b.LinesChanged,
+		Deleted:       b.Deleted,
+	}
+}
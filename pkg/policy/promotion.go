@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+)
+
+// DeploymentHistory reports which commits were deployed to which environments
+type DeploymentHistory interface {
+	Deployed(environment, sha string) bool
+}
+
+// WithHistory sets the deployment history used to check promotion chains
+func WithHistory(history DeploymentHistory) Option {
+	return func(e *Engine) {
+		e.history = history
+	}
+}
+
+// compilePromotion checks the settings of every promotion chain
+func compilePromotion(cfg *config.Config) []error {
+	var errs []error
+	for i, chain := range cfg.Promotion.Chains {
+		switch chain.OnIneligible {
+		case "", "warn", "block":
+		default:
+			errs = append(errs, fmt.Errorf("promotion.chains[%d].on_ineligible: invalid value %q (expected warn or block)", i, chain.OnIneligible))
+		}
+		if len(chain.Environments) < 2 {
+			errs = append(errs, fmt.Errorf("promotion.chains[%d].environments: a chain needs at least two environments", i))
+		}
+	}
+	return errs
+}
+
+// applyPromotion checks that a commit being deployed to an environment in a
+// promotion chain was already deployed to the previous environment
+func (e *Engine) applyPromotion(decision *Decision, trace *Trace) {
+	for i, chain := range e.config.Promotion.Chains {
+		previous := previousEnvironment(chain.Environments, decision.Environment)
+		if previous == "" || !decision.ShouldDeploy {
+			continue
+		}
+
+		switch {
+		case e.history == nil:
+			decision.addFinding(SeverityInfo, FindingPromotionUnchecked,
+				fmt.Sprintf("Promotion to %s not checked: no deployment history available", decision.Environment))
+			continue
+		case decision.CommitSHA == "":
+			decision.addFinding(SeverityInfo, FindingPromotionUnchecked,
+				fmt.Sprintf("Promotion to %s not checked: commit SHA unknown", decision.Environment))
+			continue
+		case e.history.Deployed(previous, decision.CommitSHA):
+			continue
+		}
+
+		message := fmt.Sprintf("Commit %s has not been deployed to %s; %s only receives commits promoted from %s",
+			shortSHA(decision.CommitSHA), previous, decision.Environment, previous)
+		if chain.OnIneligible != "block" {
+			decision.addFinding(SeverityWarn, FindingPromotionIneligible, message)
+			continue
+		}
+
+		trace.change(fmt.Sprintf("promotion.chains[%d]", i), "should_deploy", decision.ShouldDeploy, false, message)
+		decision.ShouldDeploy = false
+		decision.addFinding(SeverityError, FindingPromotionIneligible, message)
+	}
+}
+
+// previousEnvironment returns the environment before env in a chain, or ""
+func previousEnvironment(chain []string, env string) string {
+	for i := 1; i < len(chain); i++ {
+		if chain[i] == env {
+			return chain[i-1]
+		}
+	}
+	return ""
+}
+
+// shortSHA abbreviates a commit SHA for messages
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
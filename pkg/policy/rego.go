@@ -0,0 +1,210 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+	"gopkg.in/yaml.v3"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+)
+
+// RegoQuery is the document a Rego policy must define
+const RegoQuery = "data.branchci"
+
+// RegoPolicy is a compiled Rego policy evaluated on top of the built-in
+// decision. The policy receives a RegoInput and defines the branchci package,
+// whose decision, deny and warn rules are described by regoOutput.
+type RegoPolicy struct {
+	path  string
+	query rego.PreparedEvalQuery
+}
+
+// RegoInput is the input document of a Rego policy
+type RegoInput struct {
+	Branch          PluginBranch `json:"branch"`
+	Config          interface{}  `json:"config"` // The configuration with the keys of the config file
+	DefaultDecision *Decision    `json:"default_decision"`
+}
+
+// regoOutput is the branchci document a Rego policy defines
+type regoOutput struct {
+	Decision *regoDecision `json:"decision"`
+	Deny     []string      `json:"deny"` // Reasons to block the deployment
+	Warn     []string      `json:"warn"`
+}
+
+// regoDecision holds the decision fields a policy replaces; fields it does
+// not define keep the built-in engine's values
+type regoDecision struct {
+	Environment      *string           `json:"environment"`
+	ShouldDeploy     *bool             `json:"should_deploy"`
+	RequiresApproval *bool             `json:"requires_approval"`
+	Actions          *[]string         `json:"actions"`
+	Variables        map[string]string `json:"variables"` // Set on top of the default variables
+}
+
+// LoadRego compiles the Rego policy at path, a .rego file or a bundle
+// directory of .rego and data files
+func LoadRego(path string) (*RegoPolicy, error) {
+	query, err := rego.New(
+		rego.Query(RegoQuery),
+		rego.Load([]string{path}, nil),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Rego policy %s: %w", path, err)
+	}
+	return &RegoPolicy{path: path, query: query}, nil
+}
+
+// WithRego sets a Rego policy evaluated on every decision
+func WithRego(policy *RegoPolicy) Option {
+	return func(e *Engine) {
+		e.rego = policy
+	}
+}
+
+// eval runs the policy and decodes the branchci document
+func (p *RegoPolicy) eval(input *RegoInput) (*regoOutput, error) {
+	// Round-trip the input through JSON so the policy sees the JSON field names
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	results, err := p.query.Eval(context.Background(), rego.EvalInput(doc))
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return nil, errors.New("the policy does not define package branchci")
+	}
+
+	data, err = json.Marshal(results[0].Expressions[0].Value)
+	if err != nil {
+		return nil, err
+	}
+	output := &regoOutput{}
+	if err := json.Unmarshal(data, output); err != nil {
+		return nil, fmt.Errorf("invalid branchci document: %w", err)
+	}
+	return output, nil
+}
+
+// applyRego evaluates the Rego policy with the built-in decision for one
+// environment as input.default_decision and applies the fields it defines.
+// A new environment is applied like a rule's set_environment: its settings
+// replace those of the old one, keeping approval required by code review,
+// rules, risk thresholds and plugins, and a template environment is rendered.
+// New actions are ordered and planned with the action catalog.
+func (e *Engine) applyRego(decision *Decision, branchInfo *git.BranchInfo, trace *Trace) error {
+	cfg, err := configDocument(e.config)
+	if err != nil {
+		return err
+	}
+
+	output, err := e.rego.eval(&RegoInput{
+		Branch:          pluginBranch(branchInfo),
+		Config:          cfg,
+		DefaultDecision: decision,
+	})
+	if err != nil {
+		return fmt.Errorf("rego policy %s: %w", e.rego.path, err)
+	}
+
+	const source = "rego"
+	reason := "rego policy " + e.rego.path
+	if d := output.Decision; d != nil {
+		replan := false
+		if d.Environment != nil && *d.Environment != decision.Environment {
+			if err := e.changeEnvironment(decision, branchInfo, *d.Environment, reason, trace); err != nil {
+				return err
+			}
+			replan = true
+		}
+		if d.ShouldDeploy != nil {
+			trace.change(source, "should_deploy", decision.ShouldDeploy, *d.ShouldDeploy, reason)
+			decision.ShouldDeploy = *d.ShouldDeploy
+		}
+		if d.RequiresApproval != nil {
+			trace.change(source, "requires_approval", decision.RequiresApproval, *d.RequiresApproval, reason)
+			decision.RequiresApproval = *d.RequiresApproval
+		}
+		if d.Actions != nil {
+			actions := *d.Actions
+			if actions == nil {
+				actions = []string{}
+			}
+			trace.change(source, "actions", decision.Actions, actions, reason)
+			decision.Actions = actions
+			replan = true
+		}
+		for _, k := range sortedKeys(d.Variables) {
+			decision.Variables[k] = d.Variables[k]
+			trace.variable(k, d.Variables[k], source)
+		}
+		if replan {
+			decision.removeFindings(FindingUnknownAction)
+			e.applyActions(decision, trace)
+		}
+	}
+
+	for _, warning := range output.Warn {
+		decision.addFinding(SeverityWarn, FindingRegoWarning, warning)
+	}
+	if len(output.Deny) > 0 {
+		trace.change(source, "should_deploy", decision.ShouldDeploy, false, reason)
+		decision.ShouldDeploy = false
+		for _, message := range output.Deny {
+			decision.addFinding(SeverityError, FindingRegoDenied, message)
+		}
+	}
+	return nil
+}
+
+// changeEnvironment moves the decision to the environment a Rego policy set
+func (e *Engine) changeEnvironment(decision *Decision, branchInfo *git.BranchInfo, environment, reason string, trace *Trace) error {
+	trace.change("rego", "environment", decision.Environment, environment, reason)
+	decision.Environment = environment
+	decision.Ephemeral, decision.Teardown = false, false
+	decision.removeFindings(FindingBranchNotAllowed)
+
+	if err := e.applyEnvironment(decision, branchInfo, trace); err != nil {
+		return err
+	}
+	e.applyCodeReview(decision, branchInfo, trace)
+	if decision.approvalRaised {
+		decision.RequiresApproval = true
+	}
+
+	if err := e.applyTemplate(decision, branchInfo, trace); err != nil {
+		return err
+	}
+	if branchInfo.Deleted {
+		decision.removeFindings(FindingBranchDeleted)
+		applyTeardown(decision, branchInfo, trace)
+	}
+	return nil
+}
+
+// configDocument converts the configuration into a document with the keys of
+// the config file
+func configDocument(cfg *config.Config) (interface{}, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
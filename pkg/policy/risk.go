@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+)
+
+// Risk is the risk score of a decision and the factors that make it up
+type Risk struct {
+	Score   int          `json:"score" yaml:"score"`
+	Level   string       `json:"level,omitempty" yaml:"level,omitempty"` // Name of the highest threshold reached
+	Factors []RiskFactor `json:"factors,omitempty" yaml:"factors,omitempty"`
+}
+
+// RiskFactor is one contribution to a risk score
+type RiskFactor struct {
+	Factor string `json:"factor" yaml:"factor"` // branch_type, environment, changes, sensitive_path or off_hours
+	Points int    `json:"points" yaml:"points"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// String describes the score, e.g. "72 (high)"
+func (r *Risk) String() string {
+	if r.Level == "" {
+		return strconv.Itoa(r.Score)
+	}
+	return fmt.Sprintf("%d (%s)", r.Score, r.Level)
+}
+
+// compileRisk checks the risk settings and returns the time zone of the
+// working hours
+func compileRisk(cfg *config.Config) (*time.Location, []error) {
+	if cfg.Risk == nil || cfg.Risk.OffHours == nil {
+		return time.UTC, nil
+	}
+
+	var errs []error
+	offHours := cfg.Risk.OffHours
+	if offHours.Start < 0 || offHours.Start > 23 {
+		errs = append(errs, fmt.Errorf("risk.off_hours.start: hour %d out of range 0-23", offHours.Start))
+	}
+	if offHours.End < 0 || offHours.End > 23 {
+		errs = append(errs, fmt.Errorf("risk.off_hours.end: hour %d out of range 0-23", offHours.End))
+	}
+
+	location := time.UTC
+	if offHours.Timezone != "" {
+		loc, err := time.LoadLocation(offHours.Timezone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("risk.off_hours.timezone: invalid timezone %q: %w", offHours.Timezone, err))
+		} else {
+			location = loc
+		}
+	}
+	return location, errs
+}
+
+// applyRisk scores the decision and applies the effects of every threshold
+// the score reaches, from the lowest to the highest
+func (e *Engine) applyRisk(decision *Decision, branchInfo *git.BranchInfo, now time.Time, trace *Trace) {
+	cfg := e.config.Risk
+	if cfg == nil {
+		return
+	}
+
+	risk := &Risk{}
+	add := func(factor string, points int, reason string) {
+		if points != 0 {
+			risk.Factors = append(risk.Factors, RiskFactor{Factor: factor, Points: points, Reason: reason})
+			risk.Score += points
+		}
+	}
+
+	add("branch_type", cfg.BranchTypes[branchInfo.Type], branchInfo.Type+" branch")
+	add("environment", cfg.Environments[decision.Environment], "deploys to "+decision.Environment)
+
+	files, lines := len(branchInfo.ChangedFiles), branchInfo.LinesChanged
+	points := files*cfg.Changes.PerFile + lines*cfg.Changes.PerHundredLines/100
+	if cfg.Changes.Max > 0 && points > cfg.Changes.Max {
+		points = cfg.Changes.Max
+	}
+	reason := fmt.Sprintf("%d files changed", files)
+	if cfg.Changes.PerHundredLines != 0 {
+		reason = fmt.Sprintf("%d files, %d lines changed", files, lines)
+	}
+	add("changes", points, reason)
+
+	for _, path := range cfg.SensitivePaths {
+		for _, file := range branchInfo.ChangedFiles {
+			if e.patterns.Match(file, path.Pattern) {
+				add("sensitive_path", path.Weight, fmt.Sprintf("%s matches %s", file, path.Pattern))
+				break
+			}
+		}
+	}
+
+	if cfg.OffHours != nil {
+		if reason, off := offHours(cfg.OffHours, now.In(e.riskLocation)); off {
+			add("off_hours", cfg.OffHours.Weight, reason)
+		}
+	}
+
+	thresholds := make([]int, len(cfg.Thresholds))
+	for i := range thresholds {
+		thresholds[i] = i
+	}
+	sort.SliceStable(thresholds, func(a, b int) bool {
+		return cfg.Thresholds[thresholds[a]].Score < cfg.Thresholds[thresholds[b]].Score
+	})
+
+	for _, i := range thresholds {
+		threshold := cfg.Thresholds[i]
+		if risk.Score < threshold.Score {
+			break
+		}
+
+		source := fmt.Sprintf("risk.thresholds[%d]", i)
+		reason := fmt.Sprintf("risk score %d reached %d", risk.Score, threshold.Score)
+		if threshold.Name != "" {
+			risk.Level = threshold.Name
+		}
+
+		if len(threshold.AddActions) > 0 {
+			actions := append([]string{}, decision.Actions...)
+			for _, action := range threshold.AddActions {
+				if !contains(actions, action) {
+					actions = append(actions, action)
+				}
+			}
+			trace.change(source, "actions", decision.Actions, actions, reason)
+			decision.Actions = actions
+		}
+
+		if threshold.RequireApproval {
+			trace.change(source, "requires_approval", decision.RequiresApproval, true, reason)
+			decision.RequiresApproval = true
+			decision.approvalRaised = true
+		}
+
+		decision.addFinding(SeverityInfo, FindingRiskThreshold,
+			fmt.Sprintf("Risk score %d reached the %s threshold (%d)", risk.Score, thresholdName(threshold, i), threshold.Score))
+	}
+
+	decision.Risk = risk
+}
+
+// offHours reports whether t falls outside working hours, and why
+func offHours(cfg *config.RiskOffHours, t time.Time) (string, bool) {
+	if cfg.Weekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return "deploying on a " + t.Weekday().String(), true
+	}
+
+	hour := t.Hour()
+	var off bool
+	switch {
+	case cfg.Start < cfg.End:
+		off = hour >= cfg.Start && hour < cfg.End
+	case cfg.Start > cfg.End:
+		off = hour >= cfg.Start || hour < cfg.End
+	}
+	if !off {
+		return "", false
+	}
+	return fmt.Sprintf("deploying at %s, outside working hours", t.Format("15:04 MST")), true
+}
+
+// thresholdName returns the threshold's name, or its position when unnamed
+func thresholdName(threshold config.RiskThreshold, index int) string {
+	if threshold.Name != "" {
+		return threshold.Name
+	}
+	return fmt.Sprintf("#%d", index)
+}
@@ -0,0 +1,160 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/expr"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+)
+
+// compileRules compiles the when expression of every policy rule
+func compileRules(cfg *config.Config) ([]*expr.Expression, []error) {
+	rules := make([]*expr.Expression, len(cfg.Policies.Rules))
+	var errs []error
+
+	for i, rule := range cfg.Policies.Rules {
+		if rule.Severity != "" {
+			if _, err := ParseSeverity(rule.Severity); err != nil {
+				errs = append(errs, fmt.Errorf("policies.rules[%d].severity: %w", i, err))
+			}
+		}
+		if rule.When == "" {
+			errs = append(errs, fmt.Errorf("policies.rules[%d].when: expression is required", i))
+			continue
+		}
+		compiled, err := expr.Compile(rule.When)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("policies.rules[%d].when: %w", i, err))
+			continue
+		}
+		rules[i] = compiled
+	}
+
+	return rules, errs
+}
+
+// ruleEnv builds the values visible to rule expressions
+func ruleEnv(decision *Decision, branchInfo *git.BranchInfo, now time.Time) expr.Env {
+	now = now.UTC()
+	metadata := make(map[string]interface{}, len(branchInfo.Metadata))
+	for k, v := range branchInfo.Metadata {
+		metadata[k] = v
+	}
+
+	return expr.Env{
+		"branch": map[string]interface{}{
+			"name":      branchInfo.ShortName,
+			"full_name": branchInfo.Name,
+			"type":      branchInfo.Type,
+			"protected": branchInfo.IsProtected,
+		},
+		"metadata": metadata,
+		"commit": map[string]interface{}{
+			"sha":           branchInfo.CommitSHA,
+			"message":       branchInfo.CommitMessage,
+			"author":        branchInfo.CommitAuthor,
+			"files":         branchInfo.ChangedFiles,
+			"files_changed": len(branchInfo.ChangedFiles),
+			"lines_changed": branchInfo.LinesChanged,
+		},
+		"target": branchInfo.TargetBranch,
+		"time": map[string]interface{}{
+			"hour":    now.Hour(),
+			"minute":  now.Minute(),
+			"weekday": now.Weekday().String(),
+			"date":    now.Format("2006-01-02"),
+			"unix":    now.Unix(),
+		},
+		"environment":       decision.Environment,
+		"should_deploy":     decision.ShouldDeploy,
+		"requires_approval": decision.RequiresApproval,
+		"actions":           decision.Actions,
+	}
+}
+
+// applyRules evaluates the policy rules in declaration order and applies the
+// effects of every rule whose condition holds. Each rule sees the decision as
+// left by the rules before it.
+func (e *Engine) applyRules(decision *Decision, branchInfo *git.BranchInfo, now time.Time, trace *Trace) error {
+	for i, rule := range e.config.Policies.Rules {
+		matched, err := e.rules[i].EvalBool(ruleEnv(decision, branchInfo, now))
+		if err != nil {
+			return fmt.Errorf("policies.rules[%d] %s: %w", i, ruleName(rule, i), err)
+		}
+		if !matched {
+			continue
+		}
+
+		source := fmt.Sprintf("policies.rules[%d]", i)
+		reason := fmt.Sprintf("rule %s matched: %s", ruleName(rule, i), rule.When)
+
+		if rule.SetEnvironment != "" && rule.SetEnvironment != decision.Environment {
+			trace.change(source, "environment", decision.Environment, rule.SetEnvironment, reason)
+			decision.Environment = rule.SetEnvironment
+			if err := e.applyEnvironment(decision, branchInfo, trace); err != nil {
+				return err
+			}
+			e.applyCodeReview(decision, branchInfo, trace)
+		}
+
+		if len(rule.AddActions) > 0 || len(rule.RemoveActions) > 0 {
+			actions := make([]string, 0, len(decision.Actions)+len(rule.AddActions))
+			for _, action := range decision.Actions {
+				if !contains(rule.RemoveActions, action) {
+					actions = append(actions, action)
+				}
+			}
+			for _, action := range rule.AddActions {
+				if !contains(actions, action) {
+					actions = append(actions, action)
+				}
+			}
+			trace.change(source, "actions", decision.Actions, actions, reason)
+			decision.Actions = actions
+
+			// Removing the deploy action disables deployment
+			if contains(rule.RemoveActions, "deploy") && decision.ShouldDeploy {
+				trace.change(source, "should_deploy", true, false, reason)
+				decision.ShouldDeploy = false
+			}
+		}
+
+		if rule.RequireApproval {
+			trace.change(source, "requires_approval", decision.RequiresApproval, true, reason)
+			decision.RequiresApproval = true
+			decision.approvalRaised = true
+		}
+
+		for _, k := range sortedKeys(rule.SetVariables) {
+			decision.Variables[k] = rule.SetVariables[k]
+			trace.variable(k, rule.SetVariables[k], source+".set_variables")
+		}
+
+		if rule.Block {
+			trace.change(source, "should_deploy", decision.ShouldDeploy, false, reason)
+			decision.ShouldDeploy = false
+			decision.addFinding(SeverityError, FindingRuleBlocked,
+				fmt.Sprintf("Deployment blocked by rule %s", ruleName(rule, i)))
+		}
+
+		if rule.Warn != "" {
+			severity := SeverityWarn
+			if rule.Severity != "" {
+				severity = Severity(rule.Severity)
+			}
+			decision.addFinding(severity, FindingRuleWarning, rule.Warn)
+		}
+	}
+
+	return nil
+}
+
+// ruleName returns the rule's name, or its position when unnamed
+func ruleName(rule config.Rule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("#%d", index)
+}
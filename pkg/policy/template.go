@@ -0,0 +1,315 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+)
+
+// maxDNSLabel is the longest name a DNS label may have
+const maxDNSLabel = 63
+
+// templateData is the data available to variable and environment templates
+type templateData struct {
+	Branch      string            // Branch name, e.g. "feature/user-auth"
+	Slug        string            // DNS-safe branch name, e.g. "feature-user-auth"
+	Type        string            // Branch type
+	Metadata    map[string]string // Branch metadata (suffix, ticket)
+	Ticket      string            // Ticket ID from the branch name, e.g. "JIRA-123"
+	CommitSHA   string
+	Version     string // Version in the branch name, e.g. "1.4.0" for release/1.4.0
+	Environment string // Rendered environment name (the template key while rendering the name)
+}
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// newTemplateData returns the template data for a branch and environment
+func newTemplateData(branchInfo *git.BranchInfo, environment string) templateData {
+	return templateData{
+		Branch:      branchInfo.ShortName,
+		Slug:        dnsName(branchInfo.ShortName, maxDNSLabel),
+		Type:        branchInfo.Type,
+		Metadata:    branchInfo.Metadata,
+		Ticket:      branchInfo.Metadata["ticket"],
+		CommitSHA:   branchInfo.CommitSHA,
+		Version:     versionPattern.FindString(branchInfo.ShortName),
+		Environment: environment,
+	}
+}
+
+// templateFuncs are the helper functions available to templates. "var" is
+// replaced while rendering variables; elsewhere it reports an error.
+var templateFuncs = template.FuncMap{
+	"slugify":     slugify,
+	"truncate":    truncate,
+	"lower":       strings.ToLower,
+	"sha256short": sha256Short,
+	"default":     defaultValue,
+	"var": func(name string) (string, error) {
+		return "", fmt.Errorf("var %q: variables can only be referenced from other variables", name)
+	},
+}
+
+// compileTemplates parses every environment variable and the templates of
+// template environments, keyed by their location in the configuration
+func compileTemplates(cfg *config.Config) (map[string]*template.Template, []error) {
+	templates := make(map[string]*template.Template)
+	var errs []error
+
+	add := func(location, text string) {
+		tmpl, err := template.New(location).Option("missingkey=zero").Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", location, err))
+			return
+		}
+		templates[location] = tmpl
+	}
+
+	for _, name := range sortedEnvironmentNames(cfg) {
+		env := cfg.Environments[name]
+		source := "environments." + name
+		if env.Template != nil {
+			if env.Template.Name == "" {
+				errs = append(errs, fmt.Errorf("%s.template.name: a name template is required", source))
+			} else {
+				add(source+".template.name", env.Template.Name)
+			}
+			if n := env.Template.MaxLength; n != 0 && (n < 8 || n > maxDNSLabel) {
+				errs = append(errs, fmt.Errorf("%s.template.max_length: must be between 8 and %d", source, maxDNSLabel))
+			}
+			if env.Template.URL != "" {
+				add(source+".template.url", env.Template.URL)
+				if _, exists := env.Variables["PREVIEW_URL"]; exists {
+					errs = append(errs, fmt.Errorf("%s.variables.PREVIEW_URL: conflicts with %s.template.url, which sets PREVIEW_URL", source, source))
+				}
+			}
+		}
+		for _, k := range sortedKeys(env.Variables) {
+			add(source+".variables."+k, env.Variables[k])
+		}
+	}
+
+	return templates, errs
+}
+
+// render executes a compiled template, overriding template functions with funcs
+func (e *Engine) render(location string, data templateData, funcs template.FuncMap) (string, error) {
+	tmpl := e.templates[location]
+	if funcs != nil {
+		clone, err := tmpl.Clone()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", location, err)
+		}
+		tmpl = clone.Funcs(funcs)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// templateName renders the concrete environment name of a template environment
+func (e *Engine) templateName(environment string, envConfig config.EnvironmentConfig, branchInfo *git.BranchInfo) (string, error) {
+	name, err := e.render("environments."+environment+".template.name", newTemplateData(branchInfo, environment), nil)
+	if err != nil {
+		return "", fmt.Errorf("environment %s: template name: %w", environment, err)
+	}
+	if strings.TrimSpace(name) == "" {
+		return "", fmt.Errorf("environment %s: template name: rendered an empty name for branch %s", environment, branchInfo.ShortName)
+	}
+	maxLength := envConfig.Template.MaxLength
+	if maxLength == 0 {
+		maxLength = maxDNSLabel
+	}
+	return dnsName(name, maxLength), nil
+}
+
+// renderVariables renders the variables of an environment. For a template
+// environment the template URL is rendered into PREVIEW_URL.
+func (e *Engine) renderVariables(environment string, envConfig config.EnvironmentConfig, branchInfo *git.BranchInfo) (map[string]string, error) {
+	name := environment
+	if envConfig.Template != nil {
+		var err error
+		if name, err = e.templateName(environment, envConfig, branchInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	source := "environments." + environment
+	r := &variableRenderer{
+		engine:      e,
+		environment: environment,
+		data:        newTemplateData(branchInfo, name),
+		locations:   make(map[string]string, len(envConfig.Variables)+1),
+		values:      make(map[string]string, len(envConfig.Variables)+1),
+	}
+	for k := range envConfig.Variables {
+		r.locations[k] = source + ".variables." + k
+	}
+	if envConfig.Template != nil && envConfig.Template.URL != "" {
+		r.locations["PREVIEW_URL"] = source + ".template.url"
+	}
+
+	for _, k := range sortedKeys(r.locations) {
+		if _, err := r.value(k); err != nil {
+			return nil, err
+		}
+	}
+	return r.values, nil
+}
+
+// variableRenderer renders the variables of one environment, resolving
+// references between them with the "var" template function
+type variableRenderer struct {
+	engine      *Engine
+	environment string
+	data        templateData
+	locations   map[string]string // Template location of each variable
+	values      map[string]string // Rendered variables
+	stack       []string          // Variables being rendered, to detect cycles
+	err         error             // First reference error, reported instead of the wrapped one
+}
+
+// value returns the rendered value of a variable, rendering it if needed
+func (r *variableRenderer) value(name string) (string, error) {
+	if value, ok := r.values[name]; ok {
+		return value, nil
+	}
+
+	location, ok := r.locations[name]
+	if !ok {
+		return "", r.fail(fmt.Errorf("environment %s: variable %s: references unknown variable %q",
+			r.environment, r.stack[len(r.stack)-1], name))
+	}
+	for i, n := range r.stack {
+		if n == name {
+			cycle := append(append([]string{}, r.stack[i:]...), name)
+			return "", r.fail(fmt.Errorf("environment %s: variable %s: reference cycle %s",
+				r.environment, name, strings.Join(cycle, " -> ")))
+		}
+	}
+
+	r.stack = append(r.stack, name)
+	value, err := r.engine.render(location, r.data, template.FuncMap{"var": r.value})
+	r.stack = r.stack[:len(r.stack)-1]
+	if err != nil {
+		if r.err != nil {
+			return "", r.err
+		}
+		return "", r.fail(fmt.Errorf("environment %s: variable %s: %w", r.environment, name, err))
+	}
+
+	r.values[name] = value
+	return value, nil
+}
+
+// fail records the first error so that it is not reported wrapped by every
+// template that referenced the failing variable
+func (r *variableRenderer) fail(err error) error {
+	if r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// applyTemplate replaces a template environment with the concrete environment
+// rendered for the branch. It runs last so that rules, promotion chains and
+// freeze windows refer to the template by its configured name.
+func (e *Engine) applyTemplate(decision *Decision, branchInfo *git.BranchInfo, trace *Trace) error {
+	envConfig, exists := e.config.Environments[decision.Environment]
+	if !exists || envConfig.Template == nil {
+		return nil
+	}
+
+	name, err := e.templateName(decision.Environment, envConfig, branchInfo)
+	if err != nil {
+		return err
+	}
+	trace.change("environments."+decision.Environment+".template", "environment", decision.Environment, name,
+		"rendered from the environment template")
+	decision.Environment = name
+	decision.Ephemeral = true
+	return nil
+}
+
+// applyTeardown turns the decision for a deleted branch into a teardown of
+// its ephemeral environment; other environments have nothing to do
+func applyTeardown(decision *Decision, branchInfo *git.BranchInfo, trace *Trace) {
+	if !branchInfo.Deleted {
+		return
+	}
+
+	actions := []string{}
+	if decision.Ephemeral {
+		actions = []string{"teardown"}
+		decision.Teardown = true
+		decision.addFinding(SeverityInfo, FindingBranchDeleted,
+			fmt.Sprintf("Branch %s was deleted; tearing down %s", branchInfo.ShortName, decision.Environment))
+	} else {
+		decision.addFinding(SeverityInfo, FindingBranchDeleted,
+			fmt.Sprintf("Branch %s was deleted; nothing to deploy", branchInfo.ShortName))
+	}
+
+	trace.change("branch.deleted", "should_deploy", decision.ShouldDeploy, false, "branch was deleted")
+	trace.change("branch.deleted", "actions", decision.Actions, actions, "branch was deleted")
+	trace.change("branch.deleted", "requires_approval", decision.RequiresApproval, false, "branch was deleted")
+	decision.ShouldDeploy = false
+	decision.Actions = actions
+	decision.RequiresApproval = false
+}
+
+var nonDNSChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify lowercases s and replaces every run of characters other than
+// letters and digits with a single hyphen
+func slugify(s string) string {
+	return strings.Trim(nonDNSChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// dnsName returns a DNS-safe slug of name no longer than maxLength. Longer
+// names are truncated and get a hash suffix so that they stay unique; names
+// without letters or digits are replaced by their hash.
+func dnsName(name string, maxLength int) string {
+	slug := slugify(name)
+	if slug == "" {
+		return sha256Short(name)
+	}
+	if len(slug) <= maxLength {
+		return slug
+	}
+	hash := sha256Short(name)
+	return strings.TrimRight(slug[:maxLength-len(hash)-1], "-") + "-" + hash
+}
+
+// truncate shortens s to at most n characters
+func truncate(n int, s string) string {
+	if n < 0 {
+		n = 0
+	}
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// defaultValue returns value, or def when value is empty
+func defaultValue(def, value string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// sha256Short returns the first six hex digits of the SHA-256 of s
+func sha256Short(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:6]
+}
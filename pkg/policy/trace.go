@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trace records how a decision was reached
+type Trace struct {
+	Mappings      []MappingTrace  `json:"mappings" yaml:"mappings"`
+	Winner        *MappingTrace   `json:"winner,omitempty" yaml:"winner,omitempty"`
+	AllowedBranch *AllowedTrace   `json:"allowed_branch,omitempty" yaml:"allowed_branch,omitempty"`
+	Changes       []ChangeTrace   `json:"changes,omitempty" yaml:"changes,omitempty"`
+	Variables     []VariableTrace `json:"variables,omitempty" yaml:"variables,omitempty"`
+}
+
+// MappingTrace records whether a branch mapping matched
+type MappingTrace struct {
+	Index       int    `json:"index" yaml:"index"`
+	Pattern     string `json:"pattern" yaml:"pattern"`
+	Environment string `json:"environment" yaml:"environment"`
+	Priority    int    `json:"priority" yaml:"priority"`
+	Specificity int    `json:"specificity" yaml:"specificity"`
+	Matched     bool   `json:"matched" yaml:"matched"`
+}
+
+// AllowedTrace records the allowed-branch check for the chosen environment
+type AllowedTrace struct {
+	Environment string   `json:"environment" yaml:"environment"`
+	Patterns    []string `json:"patterns" yaml:"patterns"`
+	Allowed     bool     `json:"allowed" yaml:"allowed"`
+	MatchedBy   string   `json:"matched_by,omitempty" yaml:"matched_by,omitempty"`
+}
+
+// ChangeTrace records a configuration source changing a decision field
+type ChangeTrace struct {
+	Source string `json:"source" yaml:"source"`
+	Field  string `json:"field" yaml:"field"`
+	From   string `json:"from" yaml:"from"`
+	To     string `json:"to" yaml:"to"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// VariableTrace records where a decision variable came from
+type VariableTrace struct {
+	Name   string `json:"name" yaml:"name"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+// change records a field change; it is a no-op on a nil trace or when the value is unchanged
+func (t *Trace) change(source, field string, from, to interface{}, reason string) {
+	if t == nil {
+		return
+	}
+	fromStr, toStr := traceValue(from), traceValue(to)
+	if fromStr == toStr {
+		return
+	}
+	t.Changes = append(t.Changes, ChangeTrace{
+		Source: source,
+		Field:  field,
+		From:   fromStr,
+		To:     toStr,
+		Reason: reason,
+	})
+}
+
+// variable records the source of a variable; it is a no-op on a nil trace
+func (t *Trace) variable(name, value, source string) {
+	if t == nil {
+		return
+	}
+	t.Variables = append(t.Variables, VariableTrace{Name: name, Value: value, Source: source})
+}
+
+// traceValue renders a decision field value for the trace
+func traceValue(v interface{}) string {
+	switch val := v.(type) {
+	case []string:
+		return "[" + strings.Join(val, ", ") + "]"
+	default:
+		return fmt.Sprint(val)
+	}
+}
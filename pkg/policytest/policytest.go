@@ -0,0 +1,258 @@
+// Package policytest runs policy test cases: example branches with the
+// decision fields the configuration is expected to produce for them.
+package policytest
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/history"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+// DefaultPaths are the test files looked up when none is given
+var DefaultPaths = []string{".branchci.test.yml", ".branchci.test.yaml"}
+
+// Case is an input branch and the decision fields expected for it
+type Case struct {
+	Name         string       `yaml:"name"`
+	Branch       string       `yaml:"branch,omitempty"`
+	Tag          string       `yaml:"tag,omitempty"` // Evaluate a tag instead of a branch
+	Target       string       `yaml:"target,omitempty"`
+	ChangedFiles []string     `yaml:"changed_files,omitempty"`
+	LinesChanged int          `yaml:"lines_changed,omitempty"`
+	CommitSHA    string       `yaml:"commit_sha,omitempty"`
+	Deleted      bool         `yaml:"deleted,omitempty"`
+	Time         string       `yaml:"time,omitempty"`        // Evaluation time, RFC 3339 (default: now)
+	Deployments  []Deployment `yaml:"deployments,omitempty"` // Deployment history for promotion chains (default: none)
+	Expect       Expect       `yaml:"expect"`
+}
+
+// Deployment is a recorded deployment that promotion chains are checked against
+type Deployment struct {
+	Environment string `yaml:"environment"`
+	CommitSHA   string `yaml:"commit_sha"`
+}
+
+// Expect lists the expected decision fields; fields that are not set are
+// not checked
+type Expect struct {
+	Environment      *string           `yaml:"environment,omitempty"`
+	ShouldDeploy     *bool             `yaml:"should_deploy,omitempty"`
+	RequiresApproval *bool             `yaml:"requires_approval,omitempty"`
+	Actions          *[]string         `yaml:"actions,omitempty"`
+	Variables        map[string]string `yaml:"variables,omitempty"` // Only the listed variables are checked
+	Findings         *[]string         `yaml:"findings,omitempty"`  // Finding codes, in any order
+	Environments     *[]string         `yaml:"environments,omitempty"`
+}
+
+// file is the layout of a test file and of the tests section of a config file
+type file struct {
+	Tests []Case `yaml:"tests"`
+}
+
+// Load reads the test cases from the tests section of a YAML file
+func Load(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tests: %w", err)
+	}
+
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse tests in %s: %w", path, err)
+	}
+	for i, c := range f.Tests {
+		if (c.Branch == "") == (c.Tag == "") {
+			return nil, fmt.Errorf("%s: tests[%d]: set either branch or tag", path, i)
+		}
+		if c.Time != "" {
+			if _, err := time.Parse(time.RFC3339, c.Time); err != nil {
+				return nil, fmt.Errorf("%s: tests[%d].time: %w", path, i, err)
+			}
+		}
+	}
+	return f.Tests, nil
+}
+
+// Result is the outcome of one test case
+type Result struct {
+	Case     Case
+	Diffs    []string // One line per field that differs from the expectation
+	Err      error    // Set when the case could not be evaluated
+	Duration time.Duration
+}
+
+// Passed reports whether the decision matched every expectation
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+// Label returns the case name, or the ref it evaluates when unnamed
+func (c Case) Label() string {
+	switch {
+	case c.Name != "":
+		return c.Name
+	case c.Tag != "":
+		return "tag " + c.Tag
+	default:
+		return c.Branch
+	}
+}
+
+// Run evaluates every case against the configuration. The engine options
+// (plugins, a Rego policy) apply to every case; the deployment history of a
+// promotion check comes from the case.
+func Run(cfg *config.Config, cases []Case, engineOpts ...policy.Option) []Result {
+	results := make([]Result, 0, len(cases))
+	for _, c := range cases {
+		start := time.Now()
+		diffs, err := runCase(cfg, c, engineOpts)
+		results = append(results, Result{Case: c, Diffs: diffs, Err: err, Duration: time.Since(start)})
+	}
+	return results
+}
+
+// runCase evaluates a single case and compares the decision with its expectations
+func runCase(cfg *config.Config, c Case, engineOpts []policy.Option) ([]string, error) {
+	opts := append([]policy.Option{}, engineOpts...)
+	if len(cfg.Promotion.Chains) > 0 {
+		h := &history.History{}
+		for _, d := range c.Deployments {
+			h.Deployments = append(h.Deployments, history.Record{Environment: d.Environment, CommitSHA: d.CommitSHA})
+		}
+		opts = append(opts, policy.WithHistory(h))
+	}
+	if c.Time != "" {
+		now, err := time.Parse(time.RFC3339, c.Time)
+		if err != nil {
+			return nil, fmt.Errorf("time: %w", err)
+		}
+		opts = append(opts, policy.WithClock(func() time.Time { return now }))
+	}
+
+	engine, err := policy.NewEngine(cfg, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	decision, err := engine.Evaluate(branchInfo(c))
+	if err != nil {
+		return nil, err
+	}
+	return compare(c.Expect, decision), nil
+}
+
+// branchInfo builds the branch information for a case
+func branchInfo(c Case) *git.BranchInfo {
+	detector := git.NewDetector("")
+
+	var info *git.BranchInfo
+	if c.Tag != "" {
+		info = detector.GetBranchInfo(c.Tag)
+		info.Name = "refs/tags/" + c.Tag
+		info.Type = "tag"
+		info.IsProtected = false
+	} else {
+		info = detector.GetBranchInfo(c.Branch)
+	}
+
+	info.TargetBranch = c.Target
+	info.ChangedFiles = c.ChangedFiles
+	info.LinesChanged = c.LinesChanged
+	info.CommitSHA = c.CommitSHA
+	info.Deleted = c.Deleted
+	return info
+}
+
+// compare returns a line for every expected field the decision does not match
+func compare(want Expect, got *policy.Decision) []string {
+	var diffs []string
+	check := func(field string, want, got interface{}) {
+		if !reflect.DeepEqual(want, got) {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %s, got %s", field, show(want), show(got)))
+		}
+	}
+
+	if want.Environment != nil {
+		check("environment", *want.Environment, got.Environment)
+	}
+	if want.ShouldDeploy != nil {
+		check("should_deploy", *want.ShouldDeploy, got.ShouldDeploy)
+	}
+	if want.RequiresApproval != nil {
+		check("requires_approval", *want.RequiresApproval, got.RequiresApproval)
+	}
+	if want.Actions != nil {
+		check("actions", nonNil(*want.Actions), nonNil(got.Actions))
+	}
+	for _, k := range sortedKeys(want.Variables) {
+		value, exists := got.Variables[k]
+		if !exists {
+			diffs = append(diffs, fmt.Sprintf("variables.%s: expected %q, got no variable", k, want.Variables[k]))
+			continue
+		}
+		check("variables."+k, want.Variables[k], value)
+	}
+	if want.Findings != nil {
+		var codes []string
+		for _, f := range got.Findings {
+			codes = append(codes, f.Code)
+		}
+		check("findings", sorted(*want.Findings), sorted(codes))
+	}
+	if want.Environments != nil {
+		var names []string
+		for _, env := range got.Targets() {
+			names = append(names, env.Environment)
+		}
+		check("environments", nonNil(*want.Environments), nonNil(names))
+	}
+
+	return diffs
+}
+
+// show formats a value for a diff line
+func show(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []string:
+		return "[" + strings.Join(v, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// nonNil returns s, or an empty slice when s is nil
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// sorted returns a sorted copy of s
+func sorted(s []string) []string {
+	result := append([]string{}, s...)
+	sort.Strings(result)
+	return result
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,150 @@
+package policytest
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+func writeTests(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".branchci.test.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	cases, err := Load(writeTests(t, `
+tests:
+  - name: main
+    branch: main
+    expect: {environment: production, should_deploy: true}
+  - tag: v1.0.0
+    time: "2024-06-01T10:00:00Z"
+    expect: {actions: []}
+`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cases) != 2 || cases[1].Label() != "tag v1.0.0" {
+		t.Fatalf("Unexpected cases: %+v", cases)
+	}
+	if cases[0].Expect.RequiresApproval != nil || cases[1].Expect.Actions == nil || len(*cases[1].Expect.Actions) != 0 {
+		t.Errorf("Expected only the listed fields to be checked: %+v", cases[1].Expect)
+	}
+
+	for _, content := range []string{
+		"tests:\n  - expect: {}\n",
+		"tests:\n  - {branch: main, tag: v1}\n",
+		"tests:\n  - {branch: main, time: tomorrow}\n",
+	} {
+		if _, err := Load(writeTests(t, content)); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	cases, err := Load(writeTests(t, `
+tests:
+  - name: main deploys
+    branch: main
+    expect:
+      environment: production
+      should_deploy: true
+      actions: [deploy, notify, test]
+      findings: []
+  - name: wrong expectations
+    branch: feature/login
+    target: main
+    changed_files: [src/login.go]
+    expect:
+      environment: staging
+      should_deploy: true
+      variables: {ENV: development, MISSING: x}
+  - name: freeze window
+    branch: main
+    time: "2024-12-25T12:00:00Z"
+    expect: {should_deploy: false, findings: [freeze_window]}
+`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.FreezeWindows = []config.FreezeWindow{{Name: "holidays", Start: "2024-12-20", End: "2025-01-02"}}
+	results := Run(cfg, cases)
+
+	if !results[0].Passed() {
+		t.Errorf("Expected %s to pass, got %v %v", results[0].Case.Label(), results[0].Diffs, results[0].Err)
+	}
+	want := []string{
+		`environment: expected "staging", got "development"`,
+		`should_deploy: expected true, got false`,
+		`variables.MISSING: expected "x", got no variable`,
+	}
+	if got := strings.Join(results[1].Diffs, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("Unexpected diffs:\n%s", got)
+	}
+	if !results[2].Passed() {
+		t.Errorf("Expected %s to pass, got %v %v", results[2].Case.Label(), results[2].Diffs, results[2].Err)
+	}
+
+	report, err := JUnit("policies", results)
+	if err != nil {
+		t.Fatalf("JUnit failed: %v", err)
+	}
+	var parsed junitSuites
+	if err := xml.Unmarshal(report, &parsed); err != nil {
+		t.Fatalf("Invalid JUnit XML: %v\n%s", err, report)
+	}
+	suite := parsed.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Errors != 0 || suite.Cases[1].Failure == nil {
+		t.Errorf("Unexpected JUnit suite: %+v", suite)
+	}
+}
+
+func TestRunWithHistoryAndRego(t *testing.T) {
+	cases, err := Load(writeTests(t, `
+tests:
+  - name: promoted from staging
+    branch: main
+    commit_sha: abc1234
+    deployments:
+      - {environment: staging, commit_sha: abc1234}
+    expect: {should_deploy: true, findings: [rego_warning]}
+  - name: not deployed to staging
+    branch: main
+    commit_sha: abc1234
+    expect: {should_deploy: false, findings: [promotion_ineligible, rego_warning]}
+`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "policy.rego")
+	if err := os.WriteFile(path, []byte("package branchci\n\nwarn contains \"checked by rego\" if true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	regoPolicy, err := policy.LoadRego(path)
+	if err != nil {
+		t.Fatalf("LoadRego failed: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Promotion.Chains = []config.PromotionChain{
+		{Name: "release", Environments: []string{"staging", "production"}, OnIneligible: "block"},
+	}
+	for _, r := range Run(cfg, cases, policy.WithRego(regoPolicy)) {
+		if !r.Passed() {
+			t.Errorf("Expected %s to pass, got %v %v", r.Case.Label(), r.Diffs, r.Err)
+		}
+	}
+}
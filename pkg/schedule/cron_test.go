@@ -0,0 +1,100 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronMatches(t *testing.T) {
+	// 2026-10-16 is a Friday
+	friday := time.Date(2026, 10, 16, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		at       time.Time
+		expected bool
+	}{
+		{"* * * * *", friday, true},
+		{"30 14 * * FRI", friday, true},
+		{"30 14 * * 5", friday, true},
+		{"30 14 * * MON-THU", friday, false},
+		{"*/15 * * * *", friday, true},
+		{"*/20 * * * *", friday, false},
+		{"0-30/10 14 * * *", friday, true},
+		{"30 9-17 * OCT *", friday, true},
+		{"30 14 * JAN,FEB *", friday, false},
+		{"30 14 16 * *", friday, true},
+		{"30 14 1 * SUN", friday, false},
+		// Day of month or day of week when both are restricted
+		{"30 14 16 * SUN", friday, true},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 * * 0", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron failed: %v", err)
+			}
+			if got := c.Matches(tt.at); got != tt.expected {
+				t.Errorf("Expected %v at %s, got %v", tt.expected, tt.at, got)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2026-10-16 is a Friday
+	friday := time.Date(2026, 10, 16, 14, 30, 20, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 16, 14, 31, 0, 0, time.UTC)},
+		{"30 14 * * FRI", time.Date(2026, 10, 23, 14, 30, 0, 0, time.UTC)},
+		{"*/20 9-17 * * *", time.Date(2026, 10, 16, 14, 40, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * JAN MON", time.Date(2027, 1, 4, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 FEB *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 FEB *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron failed: %v", err)
+			}
+			if got := c.Next(friday); !got.Equal(tt.expected) {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * *", "expected 5 fields"},
+		{"60 * * * *", "invalid minute"},
+		{"* 24 * * *", "invalid hour"},
+		{"* * 0 * *", "invalid day of month"},
+		{"* * * FOO *", "invalid month"},
+		{"* * * * 5-1", "invalid range"},
+		{"*/0 * * * *", "invalid step"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseCron(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.1
+// source: proto/branchaware/v1/service.proto
+
+package branchawarev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	BranchDetectorService_DetectBranch_FullMethodName  = "/branchaware.v1.BranchDetectorService/DetectBranch"
+	BranchDetectorService_GetBranchInfo_FullMethodName = "/branchaware.v1.BranchDetectorService/GetBranchInfo"
+)
+
+// BranchDetectorServiceClient is the client API for BranchDetectorService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BranchDetectorServiceClient interface {
+	DetectBranch(ctx context.Context, in *DetectBranchRequest, opts ...grpc.CallOption) (*DetectBranchResponse, error)
+	GetBranchInfo(ctx context.Context, in *GetBranchInfoRequest, opts ...grpc.CallOption) (*BranchInfo, error)
+}
+
+type branchDetectorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBranchDetectorServiceClient(cc grpc.ClientConnInterface) BranchDetectorServiceClient {
+	return &branchDetectorServiceClient{cc}
+}
+
+func (c *branchDetectorServiceClient) DetectBranch(ctx context.Context, in *DetectBranchRequest, opts ...grpc.CallOption) (*DetectBranchResponse, error) {
+	out := new(DetectBranchResponse)
+	err := c.cc.Invoke(ctx, BranchDetectorService_DetectBranch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *branchDetectorServiceClient) GetBranchInfo(ctx context.Context, in *GetBranchInfoRequest, opts ...grpc.CallOption) (*BranchInfo, error) {
+	out := new(BranchInfo)
+	err := c.cc.Invoke(ctx, BranchDetectorService_GetBranchInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BranchDetectorServiceServer is the server API for BranchDetectorService service.
+// All implementations must embed UnimplementedBranchDetectorServiceServer
+// for forward compatibility
+type BranchDetectorServiceServer interface {
+	DetectBranch(context.Context, *DetectBranchRequest) (*DetectBranchResponse, error)
+	GetBranchInfo(context.Context, *GetBranchInfoRequest) (*BranchInfo, error)
+	mustEmbedUnimplementedBranchDetectorServiceServer()
+}
+
+// UnimplementedBranchDetectorServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBranchDetectorServiceServer struct {
+}
+
+func (UnimplementedBranchDetectorServiceServer) DetectBranch(context.Context, *DetectBranchRequest) (*DetectBranchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DetectBranch not implemented")
+}
+func (UnimplementedBranchDetectorServiceServer) GetBranchInfo(context.Context, *GetBranchInfoRequest) (*BranchInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBranchInfo not implemented")
+}
+func (UnimplementedBranchDetectorServiceServer) mustEmbedUnimplementedBranchDetectorServiceServer() {}
+
+// UnsafeBranchDetectorServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BranchDetectorServiceServer will
+// result in compilation errors.
+type UnsafeBranchDetectorServiceServer interface {
+	mustEmbedUnimplementedBranchDetectorServiceServer()
+}
+
+func RegisterBranchDetectorServiceServer(s grpc.ServiceRegistrar, srv BranchDetectorServiceServer) {
+	s.RegisterService(&BranchDetectorService_ServiceDesc, srv)
+}
+
+func _BranchDetectorService_DetectBranch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DetectBranchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BranchDetectorServiceServer).DetectBranch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BranchDetectorService_DetectBranch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BranchDetectorServiceServer).DetectBranch(ctx, req.(*DetectBranchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BranchDetectorService_GetBranchInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBranchInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BranchDetectorServiceServer).GetBranchInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BranchDetectorService_GetBranchInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BranchDetectorServiceServer).GetBranchInfo(ctx, req.(*GetBranchInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BranchDetectorService_ServiceDesc is the grpc.ServiceDesc for BranchDetectorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BranchDetectorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "branchaware.v1.BranchDetectorService",
+	HandlerType: (*BranchDetectorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "DetectBranch",
+			Handler:    _BranchDetectorService_DetectBranch_Handler,
+		},
+		{
+			MethodName: "GetBranchInfo",
+			Handler:    _BranchDetectorService_GetBranchInfo_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/branchaware/v1/service.proto",
+}
+
+const (
+	PolicyEngineService_EvaluatePolicy_FullMethodName = "/branchaware.v1.PolicyEngineService/EvaluatePolicy"
+	PolicyEngineService_ValidatePolicy_FullMethodName = "/branchaware.v1.PolicyEngineService/ValidatePolicy"
+)
+
+// PolicyEngineServiceClient is the client API for PolicyEngineService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PolicyEngineServiceClient interface {
+	EvaluatePolicy(ctx context.Context, in *EvaluatePolicyRequest, opts ...grpc.CallOption) (*EvaluatePolicyResponse, error)
+	ValidatePolicy(ctx context.Context, in *ValidatePolicyRequest, opts ...grpc.CallOption) (*ValidatePolicyResponse, error)
+}
+
+type policyEngineServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPolicyEngineServiceClient(cc grpc.ClientConnInterface) PolicyEngineServiceClient {
+	return &policyEngineServiceClient{cc}
+}
+
+func (c *policyEngineServiceClient) EvaluatePolicy(ctx context.Context, in *EvaluatePolicyRequest, opts ...grpc.CallOption) (*EvaluatePolicyResponse, error) {
+	out := new(EvaluatePolicyResponse)
+	err := c.cc.Invoke(ctx, PolicyEngineService_EvaluatePolicy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyEngineServiceClient) ValidatePolicy(ctx context.Context, in *ValidatePolicyRequest, opts ...grpc.CallOption) (*ValidatePolicyResponse, error) {
+	out := new(ValidatePolicyResponse)
+	err := c.cc.Invoke(ctx, PolicyEngineService_ValidatePolicy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PolicyEngineServiceServer is the server API for PolicyEngineService service.
+// All implementations must embed UnimplementedPolicyEngineServiceServer
+// for forward compatibility
+type PolicyEngineServiceServer interface {
+	EvaluatePolicy(context.Context, *EvaluatePolicyRequest) (*EvaluatePolicyResponse, error)
+	ValidatePolicy(context.Context, *ValidatePolicyRequest) (*ValidatePolicyResponse, error)
+	mustEmbedUnimplementedPolicyEngineServiceServer()
+}
+
+// UnimplementedPolicyEngineServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPolicyEngineServiceServer struct {
+}
+
+func (UnimplementedPolicyEngineServiceServer) EvaluatePolicy(context.Context, *EvaluatePolicyRequest) (*EvaluatePolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EvaluatePolicy not implemented")
+}
+func (UnimplementedPolicyEngineServiceServer) ValidatePolicy(context.Context, *ValidatePolicyRequest) (*ValidatePolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidatePolicy not implemented")
+}
+func (UnimplementedPolicyEngineServiceServer) mustEmbedUnimplementedPolicyEngineServiceServer() {}
+
+// UnsafePolicyEngineServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PolicyEngineServiceServer will
+// result in compilation errors.
+type UnsafePolicyEngineServiceServer interface {
+	mustEmbedUnimplementedPolicyEngineServiceServer()
+}
+
+func RegisterPolicyEngineServiceServer(s grpc.ServiceRegistrar, srv PolicyEngineServiceServer) {
+	s.RegisterService(&PolicyEngineService_ServiceDesc, srv)
+}
+
+func _PolicyEngineService_EvaluatePolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluatePolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyEngineServiceServer).EvaluatePolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyEngineService_EvaluatePolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyEngineServiceServer).EvaluatePolicy(ctx, req.(*EvaluatePolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyEngineService_ValidatePolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidatePolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyEngineServiceServer).ValidatePolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyEngineService_ValidatePolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyEngineServiceServer).ValidatePolicy(ctx, req.(*ValidatePolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PolicyEngineService_ServiceDesc is the grpc.ServiceDesc for PolicyEngineService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PolicyEngineService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "branchaware.v1.PolicyEngineService",
+	HandlerType: (*PolicyEngineServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "EvaluatePolicy",
+			Handler:    _PolicyEngineService_EvaluatePolicy_Handler,
+		},
+		{
+			MethodName: "ValidatePolicy",
+			Handler:    _PolicyEngineService_ValidatePolicy_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/branchaware/v1/service.proto",
+}
+
+const (
+	ConfigService_GetConfig_FullMethodName      = "/branchaware.v1.ConfigService/GetConfig"
+	ConfigService_UpdateConfig_FullMethodName   = "/branchaware.v1.ConfigService/UpdateConfig"
+	ConfigService_ValidateConfig_FullMethodName = "/branchaware.v1.ConfigService/ValidateConfig"
+)
+
+// ConfigServiceClient is the client API for ConfigService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ConfigServiceClient interface {
+	GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*GetConfigResponse, error)
+	UpdateConfig(ctx context.Context, in *UpdateConfigRequest, opts ...grpc.CallOption) (*UpdateConfigResponse, error)
+	ValidateConfig(ctx context.Context, in *ValidateConfigRequest, opts ...grpc.CallOption) (*ValidateConfigResponse, error)
+}
+
+type configServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewConfigServiceClient(cc grpc.ClientConnInterface) ConfigServiceClient {
+	return &configServiceClient{cc}
+}
+
+func (c *configServiceClient) GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*GetConfigResponse, error) {
+	out := new(GetConfigResponse)
+	err := c.cc.Invoke(ctx, ConfigService_GetConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) UpdateConfig(ctx context.Context, in *UpdateConfigRequest, opts ...grpc.CallOption) (*UpdateConfigResponse, error) {
+	out := new(UpdateConfigResponse)
+	err := c.cc.Invoke(ctx, ConfigService_UpdateConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) ValidateConfig(ctx context.Context, in *ValidateConfigRequest, opts ...grpc.CallOption) (*ValidateConfigResponse, error) {
+	out := new(ValidateConfigResponse)
+	err := c.cc.Invoke(ctx, ConfigService_ValidateConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ConfigServiceServer is the server API for ConfigService service.
+// All implementations must embed UnimplementedConfigServiceServer
+// for forward compatibility
+type ConfigServiceServer interface {
+	GetConfig(context.Context, *GetConfigRequest) (*GetConfigResponse, error)
+	UpdateConfig(context.Context, *UpdateConfigRequest) (*UpdateConfigResponse, error)
+	ValidateConfig(context.Context, *ValidateConfigRequest) (*ValidateConfigResponse, error)
+	mustEmbedUnimplementedConfigServiceServer()
+}
+
+// UnimplementedConfigServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedConfigServiceServer struct {
+}
+
+func (UnimplementedConfigServiceServer) GetConfig(context.Context, *GetConfigRequest) (*GetConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfig not implemented")
+}
+func (UnimplementedConfigServiceServer) UpdateConfig(context.Context, *UpdateConfigRequest) (*UpdateConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateConfig not implemented")
+}
+func (UnimplementedConfigServiceServer) ValidateConfig(context.Context, *ValidateConfigRequest) (*ValidateConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateConfig not implemented")
+}
+func (UnimplementedConfigServiceServer) mustEmbedUnimplementedConfigServiceServer() {}
+
+// UnsafeConfigServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ConfigServiceServer will
+// result in compilation errors.
+type UnsafeConfigServiceServer interface {
+	mustEmbedUnimplementedConfigServiceServer()
+}
+
+func RegisterConfigServiceServer(s grpc.ServiceRegistrar, srv ConfigServiceServer) {
+	s.RegisterService(&ConfigService_ServiceDesc, srv)
+}
+
+func _ConfigService_GetConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).GetConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConfigService_GetConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).GetConfig(ctx, req.(*GetConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_UpdateConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).UpdateConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConfigService_UpdateConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).UpdateConfig(ctx, req.(*UpdateConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_ValidateConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).ValidateConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConfigService_ValidateConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).ValidateConfig(ctx, req.(*ValidateConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ConfigService_ServiceDesc is the grpc.ServiceDesc for ConfigService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ConfigService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "branchaware.v1.ConfigService",
+	HandlerType: (*ConfigServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetConfig",
+			Handler:    _ConfigService_GetConfig_Handler,
+		},
+		{
+			MethodName: "UpdateConfig",
+			Handler:    _ConfigService_UpdateConfig_Handler,
+		},
+		{
+			MethodName: "ValidateConfig",
+			Handler:    _ConfigService_ValidateConfig_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/branchaware/v1/service.proto",
+}
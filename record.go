@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/history"
+)
+
+// recordCommand records a deployment in the promotion state file
+func recordCommand(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: .branchci.yml)")
+	repoPath := fs.String("repo", ".", "Path to Git repository")
+	environment := fs.String("environment", "", "Environment the commit was deployed to (required)")
+	sha := fs.String("sha", "", "Deployed commit (default: the checked-out commit)")
+	branch := fs.String("branch", "", "Deployed branch (default: the checked-out branch)")
+	state := fs.String("state", "", "Path to the state file (default: promotion.state_file or "+history.DefaultPath+")")
+
+	fs.Parse(args)
+
+	if *environment == "" {
+		return fmt.Errorf("-environment is required")
+	}
+
+	if *sha == "" || *branch == "" {
+		branchInfo, err := git.NewDetector(*repoPath).DetectBranch()
+		if err != nil {
+			return fmt.Errorf("failed to detect branch: %w", err)
+		}
+		if *sha == "" {
+			*sha = branchInfo.CommitSHA
+		}
+		if *branch == "" {
+			*branch = branchInfo.ShortName
+		}
+	}
+	if *sha == "" {
+		return fmt.Errorf("could not determine the deployed commit; pass -sha")
+	}
+
+	path := *state
+	if path == "" {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		path = statePath(cfg)
+	}
+
+	h, err := history.Load(path)
+	if err != nil {
+		return err
+	}
+	h.Add(history.Record{
+		Environment: *environment,
+		CommitSHA:   *sha,
+		Branch:      *branch,
+		DeployedAt:  time.Now().UTC(),
+	})
+	if err := h.Save(path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Recorded deployment of %s to %s in %s\n", *sha, *environment, path)
+	return nil
+}
+
+// statePath returns the promotion state file named by the configuration
+func statePath(cfg *config.Config) string {
+	if cfg.Promotion.StateFile != "" {
+		return cfg.Promotion.StateFile
+	}
+	return history.DefaultPath
+}
@@ -2,183 +2,67 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"path/filepath"
-	"strings"
 
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/interfaces"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
 )
 
 // PolicyEngine implements the IPolicyEngine interface
-// Following Single Responsibility Principle
-type PolicyEngine struct{}
-
-// NewPolicyEngine creates a new instance of PolicyEngine
-func NewPolicyEngine() *PolicyEngine {
-	return &PolicyEngine{}
+// Following Single Responsibility Principle: adapts the shared policy core
+// (pkg/policy) used by the CLI to the service interfaces
+type PolicyEngine struct {
+	opts []policy.Option
 }
 
-// Evaluate implements IPolicyEngine.Evaluate
-func (e *PolicyEngine) Evaluate(ctx context.Context, branchInfo *interfaces.BranchInfo, config *interfaces.Config) (*interfaces.Decision, error) {
-	decision := &interfaces.Decision{
-		BranchName: branchInfo.ShortName,
-		BranchType: branchInfo.Type,
-		Actions:    []string{},
-		Variables:  make(map[string]string),
-		Warnings:   []string{},
-		Metadata:   branchInfo.Metadata,
-	}
-
-	// Find matching branch mapping
-	mapping := e.findBestMapping(branchInfo.ShortName, config)
-	if mapping == nil {
-		decision.Environment = "development"
-		decision.ShouldDeploy = false
-		decision.Warnings = append(decision.Warnings, "No matching branch mapping found, using development environment")
-	} else {
-		decision.Environment = mapping.Environment
-		decision.Actions = mapping.Actions
-		decision.ShouldDeploy = e.shouldDeploy(mapping.Actions, branchInfo.ShortName, config)
-	}
-
-	// Apply environment configuration
-	if envConfig, exists := config.Environments[decision.Environment]; exists {
-		decision.RequiresApproval = envConfig.RequiresApproval
-		for k, v := range envConfig.Variables {
-			decision.Variables[k] = v
-		}
-
-		if !e.isBranchAllowed(branchInfo.ShortName, envConfig.AllowedBranches) {
-			decision.Warnings = append(decision.Warnings,
-				fmt.Sprintf("Branch %s may not be allowed to deploy to %s",
-					branchInfo.ShortName, decision.Environment))
-		}
-	}
-
-	// Apply policies
-	e.applyPolicies(decision, branchInfo, config)
-
-	return decision, nil
-}
-
-// ValidatePolicy implements IPolicyEngine.ValidatePolicy
-func (e *PolicyEngine) ValidatePolicy(ctx context.Context, config *interfaces.Config) (bool, []string, error) {
-	var errors []string
-
-	// Validate environments
-	if len(config.Environments) == 0 {
-		errors = append(errors, "No environments defined")
-	}
-
-	// Validate branch mappings
-	if len(config.BranchMappings) == 0 {
-		errors = append(errors, "No branch mappings defined")
-	}
-
-	// Check for duplicate priorities
-	priorities := make(map[int]bool)
-	for _, mapping := range config.BranchMappings {
-		if priorities[mapping.Priority] {
-			errors = append(errors, fmt.Sprintf("Duplicate priority found: %d", mapping.Priority))
-		}
-		priorities[mapping.Priority] = true
-	}
-
-	return len(errors) == 0, errors, nil
+// NewPolicyEngine creates a new instance of PolicyEngine. The options, such
+// as the service's plugins, apply to every evaluation.
+func NewPolicyEngine(opts ...policy.Option) *PolicyEngine {
+	return &PolicyEngine{opts: opts}
 }
 
-// findBestMapping finds the best matching branch mapping based on priority
-func (e *PolicyEngine) findBestMapping(branchName string, config *interfaces.Config) *interfaces.BranchMapping {
-	var bestMatch *interfaces.BranchMapping
-	highestPriority := -1
-
-	for i := range config.BranchMappings {
-		mapping := &config.BranchMappings[i]
-		if e.matchesPattern(branchName, mapping.Pattern) {
-			if mapping.Priority > highestPriority {
-				highestPriority = mapping.Priority
-				bestMatch = mapping
-			}
-		}
-	}
-
-	return bestMatch
+// Evaluate implements IPolicyEngine.Evaluate
+func (e *PolicyEngine) Evaluate(ctx context.Context, branchInfo *interfaces.BranchInfo, cfg *interfaces.Config) (*interfaces.Decision, error) {
+	return e.evaluate(branchInfo, cfg, (*policy.Engine).Evaluate)
 }
 
-// matchesPattern checks if branch name matches a pattern
-func (e *PolicyEngine) matchesPattern(branchName, pattern string) bool {
-	if branchName == pattern {
-		return true
-	}
-
-	if strings.HasSuffix(pattern, "/*") {
-		prefix := strings.TrimSuffix(pattern, "/*")
-		return strings.HasPrefix(branchName, prefix+"/")
-	}
-
-	matched, _ := filepath.Match(pattern, branchName)
-	return matched
+// Explain implements IPolicyExplainer.Explain
+func (e *PolicyEngine) Explain(ctx context.Context, branchInfo *interfaces.BranchInfo, cfg *interfaces.Config) (*interfaces.Decision, error) {
+	return e.evaluate(branchInfo, cfg, (*policy.Engine).Explain)
 }
 
-// shouldDeploy determines if deployment should occur
-func (e *PolicyEngine) shouldDeploy(actions []string, branchName string, config *interfaces.Config) bool {
-	for _, action := range actions {
-		if action == "deploy" {
-			return true
-		}
+// evaluate converts the request, runs it through the policy core and converts the result back
+func (e *PolicyEngine) evaluate(branchInfo *interfaces.BranchInfo, cfg *interfaces.Config,
+	run func(*policy.Engine, *git.BranchInfo) (*policy.Decision, error)) (*interfaces.Decision, error) {
+	if branchInfo == nil {
+		return nil, errors.New("branch info is required")
 	}
-
-	for _, autoBranch := range config.Policies.AutoDeployBranches {
-		if branchName == autoBranch {
-			return true
-		}
+	if cfg == nil {
+		return nil, errors.New("config is required")
 	}
 
-	return false
-}
-
-// isBranchAllowed checks if branch is allowed for the environment
-func (e *PolicyEngine) isBranchAllowed(branchName string, allowedPatterns []string) bool {
-	if len(allowedPatterns) == 0 {
-		return true
+	engine, err := policy.NewEngine(config.FromInterfaces(cfg), e.opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
-	for _, pattern := range allowedPatterns {
-		if e.matchesPattern(branchName, pattern) {
-			return true
-		}
+	decision, err := run(engine, git.BranchInfoFromInterfaces(branchInfo))
+	if err != nil {
+		return nil, err
 	}
 
-	return false
+	return decision.ToInterfaces(), nil
 }
 
-// applyPolicies applies policy rules to the decision
-func (e *PolicyEngine) applyPolicies(decision *interfaces.Decision, branchInfo *interfaces.BranchInfo, config *interfaces.Config) {
-	// Check blocked patterns
-	for _, blockedPattern := range config.Policies.BlockedBranchPatterns {
-		if e.matchesPattern(branchInfo.ShortName, blockedPattern) {
-			decision.ShouldDeploy = false
-			decision.Warnings = append(decision.Warnings,
-				fmt.Sprintf("Branch matches blocked pattern: %s", blockedPattern))
-		}
-	}
-
-	// Add required actions based on policies
-	if config.Policies.RequireTests && !contains(decision.Actions, "test") {
-		decision.Actions = append(decision.Actions, "test")
-	}
-
-	if config.Policies.RequireCodeReview && branchInfo.IsProtected {
-		decision.RequiresApproval = true
+// ValidatePolicy implements IPolicyEngine.ValidatePolicy
+func (e *PolicyEngine) ValidatePolicy(ctx context.Context, cfg *interfaces.Config) (bool, []string, error) {
+	if cfg == nil {
+		return false, nil, errors.New("config is required")
 	}
-}
 
-// contains checks if a slice contains a string
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
-	}
-	return false
+	problems := policy.Validate(config.FromInterfaces(cfg))
+	return len(problems) == 0, problems, nil
 }
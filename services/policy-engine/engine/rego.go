@@ -0,0 +1,16 @@
+package engine
+
+import (
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+)
+
+// NewRegoPolicyEngine creates a PolicyEngine that evaluates the Rego policy
+// at path, a .rego file or a bundle directory, on top of every built-in
+// decision. The policy sees the built-in result as input.default_decision.
+func NewRegoPolicyEngine(path string, opts ...policy.Option) (*PolicyEngine, error) {
+	regoPolicy, err := policy.LoadRego(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPolicyEngine(append(opts, policy.WithRego(regoPolicy))...), nil
+}
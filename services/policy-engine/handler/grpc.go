@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/interfaces"
+	pb "github.com/NadeeshaMedagama/branch_aware_ci/proto/branchaware/v1"
+)
+
+// GRPCHandler serves the PolicyEngineService gRPC API
+type GRPCHandler struct {
+	pb.UnimplementedPolicyEngineServiceServer
+	engine interfaces.IPolicyEngine
+}
+
+// NewGRPCHandler creates a new gRPC handler
+func NewGRPCHandler(engine interfaces.IPolicyEngine) *GRPCHandler {
+	return &GRPCHandler{
+		engine: engine,
+	}
+}
+
+// EvaluatePolicy handles policy evaluation requests
+func (h *GRPCHandler) EvaluatePolicy(ctx context.Context, req *pb.EvaluatePolicyRequest) (*pb.EvaluatePolicyResponse, error) {
+	if req.GetBranchInfo() == nil || req.GetConfig() == nil {
+		return nil, status.Error(codes.InvalidArgument, "branch_info and config are required")
+	}
+
+	decision, err := h.engine.Evaluate(ctx, branchInfoFromProto(req.GetBranchInfo()), configFromProto(req.GetConfig()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.EvaluatePolicyResponse{Decision: decisionToProto(decision)}, nil
+}
+
+// ValidatePolicy handles policy validation requests
+func (h *GRPCHandler) ValidatePolicy(ctx context.Context, req *pb.ValidatePolicyRequest) (*pb.ValidatePolicyResponse, error) {
+	if req.GetConfig() == nil {
+		return nil, status.Error(codes.InvalidArgument, "config is required")
+	}
+
+	valid, problems, err := h.engine.ValidatePolicy(ctx, configFromProto(req.GetConfig()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.ValidatePolicyResponse{Valid: valid, Errors: problems}, nil
+}
+
+// Conversion helpers between protobuf messages and service types
+
+func branchInfoFromProto(b *pb.BranchInfo) *interfaces.BranchInfo {
+	return &interfaces.BranchInfo{
+		Name:        b.GetName(),
+		ShortName:   b.GetShortName(),
+		Type:        b.GetType(),
+		Metadata:    b.GetMetadata(),
+		IsProtected: b.GetIsProtected(),
+
+		CommitSHA:     b.GetCommitSha(),
+		CommitMessage: b.GetCommitMessage(),
+		CommitAuthor:  b.GetCommitAuthor(),
+		TargetBranch:  b.GetTargetBranch(),
+		ChangedFiles:  b.GetChangedFiles(),
+		LinesChanged:  int(b.GetLinesChanged()),
+
+		Deleted: b.GetDeleted(),
+	}
+}
+
+func configFromProto(c *pb.Config) *interfaces.Config {
+	cfg := &interfaces.Config{
+		Environments: make(map[string]interfaces.EnvironmentConfig, len(c.GetEnvironments())),
+		Policies: interfaces.PolicyConfig{
+			RequireTests:          c.GetPolicies().GetRequireTests(),
+			RequireCodeReview:     c.GetPolicies().GetRequireCodeReview(),
+			BlockedBranchPatterns: c.GetPolicies().GetBlockedBranchPatterns(),
+			AutoDeployBranches:    c.GetPolicies().GetAutoDeployBranches(),
+			Enforce:               c.GetPolicies().GetEnforce(),
+		},
+	}
+
+	for name, env := range c.GetEnvironments() {
+		cfg.Environments[name] = interfaces.EnvironmentConfig{
+			Name:             env.GetName(),
+			RequiresApproval: env.GetRequiresApproval(),
+			AllowedBranches:  env.GetAllowedBranches(),
+			Variables:        env.GetVariables(),
+			NotifyOnDeploy:   env.GetNotifyOnDeploy(),
+			Approval:         approvalFromProto(env.GetApproval()),
+			Template:         templateFromProto(env.GetTemplate()),
+		}
+	}
+
+	for _, rule := range c.GetPolicies().GetRules() {
+		cfg.Policies.Rules = append(cfg.Policies.Rules, interfaces.Rule{
+			Name:            rule.GetName(),
+			When:            rule.GetWhen(),
+			SetEnvironment:  rule.GetSetEnvironment(),
+			AddActions:      rule.GetAddActions(),
+			RemoveActions:   rule.GetRemoveActions(),
+			RequireApproval: rule.GetRequireApproval(),
+			Block:           rule.GetBlock(),
+			SetVariables:    rule.GetSetVariables(),
+			Warn:            rule.GetWarn(),
+			Severity:        rule.GetSeverity(),
+		})
+	}
+
+	for _, window := range c.GetFreezeWindows() {
+		cfg.FreezeWindows = append(cfg.FreezeWindows, interfaces.FreezeWindow{
+			Name:           window.GetName(),
+			Environments:   window.GetEnvironments(),
+			Cron:           window.GetCron(),
+			Duration:       window.GetDuration(),
+			Start:          window.GetStart(),
+			End:            window.GetEnd(),
+			Timezone:       window.GetTimezone(),
+			ExemptBranches: window.GetExemptBranches(),
+		})
+	}
+
+	cfg.Promotion.StateFile = c.GetPromotion().GetStateFile()
+	for _, chain := range c.GetPromotion().GetChains() {
+		cfg.Promotion.Chains = append(cfg.Promotion.Chains, interfaces.PromotionChain{
+			Name:         chain.GetName(),
+			Environments: chain.GetEnvironments(),
+			OnIneligible: chain.GetOnIneligible(),
+		})
+	}
+
+	cfg.Risk = riskConfigFromProto(c.GetRisk())
+
+	if len(c.GetActions()) > 0 {
+		cfg.Actions = make(map[string]interfaces.ActionConfig, len(c.GetActions()))
+		for name, action := range c.GetActions() {
+			cfg.Actions[name] = interfaces.ActionConfig{
+				Description: action.GetDescription(),
+				Needs:       action.GetNeeds(),
+				Params:      action.GetParams(),
+			}
+		}
+	}
+
+	for _, mapping := range c.GetBranchMappings() {
+		cfg.BranchMappings = append(cfg.BranchMappings, interfaces.BranchMapping{
+			Pattern:      mapping.GetPattern(),
+			Environment:  mapping.GetEnvironment(),
+			Environments: mapping.GetEnvironments(),
+			Actions:      mapping.GetActions(),
+			Priority:     int(mapping.GetPriority()),
+		})
+	}
+
+	return cfg
+}
+
+func decisionToProto(d *interfaces.Decision) *pb.Decision {
+	var environments []*pb.EnvironmentDecision
+	for _, env := range d.Environments {
+		environments = append(environments, &pb.EnvironmentDecision{
+			Environment:      env.Environment,
+			ShouldDeploy:     env.ShouldDeploy,
+			RequiresApproval: env.RequiresApproval,
+			Approval:         approvalToProto(env.Approval),
+			Risk:             riskToProto(env.Risk),
+			Ephemeral:        env.Ephemeral,
+			Teardown:         env.Teardown,
+			Actions:          env.Actions,
+			Variables:        env.Variables,
+			Warnings:         env.Warnings,
+			Findings:         findingsToProto(env.Findings),
+		})
+	}
+
+	return &pb.Decision{
+		BranchName:       d.BranchName,
+		BranchType:       d.BranchType,
+		CommitSha:        d.CommitSHA,
+		Environment:      d.Environment,
+		ShouldDeploy:     d.ShouldDeploy,
+		RequiresApproval: d.RequiresApproval,
+		Actions:          d.Actions,
+		Variables:        d.Variables,
+		Warnings:         d.Warnings,
+		Findings:         findingsToProto(d.Findings),
+		Metadata:         d.Metadata,
+		Environments:     environments,
+		Approval:         approvalToProto(d.Approval),
+		Risk:             riskToProto(d.Risk),
+		Plan:             planToProto(d.Plan),
+		Ephemeral:        d.Ephemeral,
+		Teardown:         d.Teardown,
+	}
+}
+
+func templateFromProto(t *pb.EnvironmentTemplate) *interfaces.EnvironmentTemplate {
+	if t == nil {
+		return nil
+	}
+	return &interfaces.EnvironmentTemplate{
+		Name:      t.GetName(),
+		MaxLength: int(t.GetMaxLength()),
+		URL:       t.GetUrl(),
+	}
+}
+
+func approvalFromProto(a *pb.Approval) *interfaces.Approval {
+	if a == nil {
+		return nil
+	}
+	return &interfaces.Approval{
+		Required:          int(a.GetRequired()),
+		Teams:             a.GetTeams(),
+		Users:             a.GetUsers(),
+		AllowSelfApproval: a.GetAllowSelfApproval(),
+	}
+}
+
+func approvalToProto(a *interfaces.Approval) *pb.Approval {
+	if a == nil {
+		return nil
+	}
+	return &pb.Approval{
+		Required:          int32(a.Required),
+		Teams:             a.Teams,
+		Users:             a.Users,
+		AllowSelfApproval: a.AllowSelfApproval,
+	}
+}
+
+func riskConfigFromProto(r *pb.RiskConfig) *interfaces.RiskConfig {
+	if r == nil {
+		return nil
+	}
+	risk := &interfaces.RiskConfig{
+		BranchTypes:  intMap(r.GetBranchTypes()),
+		Environments: intMap(r.GetEnvironments()),
+		Changes: interfaces.RiskChanges{
+			PerFile:         int(r.GetChanges().GetPerFile()),
+			PerHundredLines: int(r.GetChanges().GetPerHundredLines()),
+			Max:             int(r.GetChanges().GetMax()),
+		},
+	}
+	for _, path := range r.GetSensitivePaths() {
+		risk.SensitivePaths = append(risk.SensitivePaths, interfaces.RiskPath{
+			Pattern: path.GetPattern(),
+			Weight:  int(path.GetWeight()),
+		})
+	}
+	if o := r.GetOffHours(); o != nil {
+		risk.OffHours = &interfaces.RiskOffHours{
+			Start:    int(o.GetStart()),
+			End:      int(o.GetEnd()),
+			Weekends: o.GetWeekends(),
+			Timezone: o.GetTimezone(),
+			Weight:   int(o.GetWeight()),
+		}
+	}
+	for _, threshold := range r.GetThresholds() {
+		risk.Thresholds = append(risk.Thresholds, interfaces.RiskThreshold{
+			Name:            threshold.GetName(),
+			Score:           int(threshold.GetScore()),
+			RequireApproval: threshold.GetRequireApproval(),
+			AddActions:      threshold.GetAddActions(),
+		})
+	}
+	return risk
+}
+
+func riskToProto(r *interfaces.Risk) *pb.Risk {
+	if r == nil {
+		return nil
+	}
+	risk := &pb.Risk{Score: int32(r.Score), Level: r.Level}
+	for _, f := range r.Factors {
+		risk.Factors = append(risk.Factors, &pb.RiskFactor{Factor: f.Factor, Points: int32(f.Points), Reason: f.Reason})
+	}
+	return risk
+}
+
+func planToProto(plan []interfaces.PlanStage) []*pb.PlanStage {
+	var result []*pb.PlanStage
+	for _, stage := range plan {
+		s := &pb.PlanStage{}
+		for _, a := range stage.Actions {
+			s.Actions = append(s.Actions, &pb.PlannedAction{Name: a.Name, Needs: a.Needs, Params: a.Params})
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func intMap(m map[string]int32) map[string]int {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string]int, len(m))
+	for k, v := range m {
+		result[k] = int(v)
+	}
+	return result
+}
+
+func findingsToProto(findings []interfaces.Finding) []*pb.Finding {
+	var result []*pb.Finding
+	for _, f := range findings {
+		result = append(result, &pb.Finding{Severity: f.Severity, Code: f.Code, Message: f.Message})
+	}
+	return result
+}
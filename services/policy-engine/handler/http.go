@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/interfaces"
 )
@@ -31,20 +32,41 @@ type EvaluatePolicyResponse struct {
 	Error    string               `json:"error,omitempty"`
 }
 
-// EvaluatePolicy handles policy evaluation requests
+// EvaluatePolicy handles policy evaluation requests.
+// With ?explain=true the decision includes a trace of how it was reached.
 func (h *HTTPHandler) EvaluatePolicy(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	explain := false
+	if value := r.URL.Query().Get("explain"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(w, "Invalid explain parameter", http.StatusBadRequest)
+			return
+		}
+		explain = parsed
+	}
+
 	var req EvaluatePolicyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	decision, err := h.engine.Evaluate(r.Context(), req.BranchInfo, req.Config)
+	evaluate := h.engine.Evaluate
+	if explain {
+		explainer, ok := h.engine.(interfaces.IPolicyExplainer)
+		if !ok {
+			respondWithError(w, "Policy engine does not support explain", http.StatusNotImplemented)
+			return
+		}
+		evaluate = explainer.Explain
+	}
+
+	decision, err := evaluate(r.Context(), req.BranchInfo, req.Config)
 	if err != nil {
 		respondWithError(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
@@ -9,13 +11,17 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
 	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/interfaces"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+	pb "github.com/NadeeshaMedagama/branch_aware_ci/proto/branchaware/v1"
 	"github.com/NadeeshaMedagama/branch_aware_ci/services/policy-engine/engine"
 	"github.com/NadeeshaMedagama/branch_aware_ci/services/policy-engine/handler"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -27,8 +33,22 @@ func main() {
 	grpcPort := getEnv("GRPC_PORT", defaultGRPCPort)
 	httpPort := getEnv("HTTP_PORT", defaultHTTPPort)
 
+	// Plugins are configured on the server, never by the request
+	var opts []policy.Option
+	if path := os.Getenv("PLUGINS_CONFIG"); path != "" {
+		plugins, err := loadPlugins(path)
+		if err != nil {
+			log.Fatalf("Failed to load plugins: %v", err)
+		}
+		log.Printf("Loaded %d policy plugins from %s", len(plugins), path)
+		opts = append(opts, policy.WithPlugins(plugins))
+	}
+
 	// Create policy engine instance
-	var policyEngine interfaces.IPolicyEngine = engine.NewPolicyEngine()
+	policyEngine, err := newPolicyEngine(opts)
+	if err != nil {
+		log.Fatalf("Failed to create policy engine: %v", err)
+	}
 
 	// Start gRPC server
 	go startGRPCServer(grpcPort, policyEngine)
@@ -51,6 +71,7 @@ func startGRPCServer(port string, engine interfaces.IPolicyEngine) {
 	}
 
 	grpcServer := grpc.NewServer()
+	pb.RegisterPolicyEngineServiceServer(grpcServer, handler.NewGRPCHandler(engine))
 
 	healthServer := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
@@ -86,6 +107,37 @@ func startHTTPServer(port string, engine interfaces.IPolicyEngine) {
 	}
 }
 
+// newPolicyEngine creates the engine selected by POLICY_ENGINE: the built-in
+// engine, or the rego engine with the Rego policy at REGO_POLICY
+func newPolicyEngine(opts []policy.Option) (interfaces.IPolicyEngine, error) {
+	switch name := getEnv("POLICY_ENGINE", "builtin"); name {
+	case "builtin":
+		return engine.NewPolicyEngine(opts...), nil
+	case "rego":
+		path := os.Getenv("REGO_POLICY")
+		if path == "" {
+			return nil, errors.New("POLICY_ENGINE=rego requires REGO_POLICY")
+		}
+		log.Printf("Evaluating Rego policy %s", path)
+		return engine.NewRegoPolicyEngine(path, opts...)
+	default:
+		return nil, fmt.Errorf("invalid POLICY_ENGINE %q (expected builtin or rego)", name)
+	}
+}
+
+// loadPlugins reads the plugins section of a config file
+func loadPlugins(path string) ([]*policy.Plugin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return policy.NewPlugins(cfg.Plugins)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
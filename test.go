@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policytest"
+)
+
+// testCommand runs the policy test cases against the configuration
+func testCommand(args []string) error {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to config file (default: .branchci.yml)")
+	testsPath := fs.String("tests", "", "Path to test cases (default: .branchci.test.yml, else the config's tests section)")
+	junitPath := fs.String("junit", "", "Also write a JUnit XML report to this path")
+	engine := fs.String("engine", "builtin", "Policy engine (builtin, rego)")
+	regoPolicy := fs.String("rego", "", "Rego policy file or bundle directory for -engine rego")
+
+	fs.Parse(args)
+
+	if *configPath == "" {
+		*configPath = config.FindConfigFile()
+	}
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	path := *testsPath
+	if path == "" {
+		path = testsFile(*configPath)
+	}
+	if path == "" {
+		return errors.New("no test cases found; add .branchci.test.yml or a tests section to the config")
+	}
+	cases, err := policytest.Load(path)
+	if err != nil {
+		return err
+	}
+	if len(cases) == 0 {
+		return fmt.Errorf("no test cases in %s", path)
+	}
+
+	// Cases run with the same plugins and Rego policy as a real evaluation
+	engineOpts, err := engineOptions(cfg, *engine, *regoPolicy)
+	if err != nil {
+		return err
+	}
+	results := policytest.Run(cfg, cases, engineOpts...)
+
+	if *junitPath != "" {
+		report, err := policytest.JUnit("branch-aware-ci", results)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*junitPath, report, 0644); err != nil {
+			return fmt.Errorf("failed to write JUnit report: %w", err)
+		}
+	}
+
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Printf("❌ %s\n    error: %v\n", r.Case.Label(), r.Err)
+		case len(r.Diffs) > 0:
+			failed++
+			fmt.Printf("❌ %s\n", r.Case.Label())
+			for _, diff := range r.Diffs {
+				fmt.Printf("    %s\n", diff)
+			}
+		default:
+			fmt.Printf("✅ %s\n", r.Case.Label())
+		}
+	}
+
+	fmt.Printf("\n%d tests, %d passed, %d failed\n", len(results), len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d policy tests failed", failed, len(results))
+	}
+	return nil
+}
+
+// testsFile returns the default test file, or the config file when it has
+// no separate test file
+func testsFile(configPath string) string {
+	for _, path := range policytest.DefaultPaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if _, err := os.Stat(configPath); configPath != "" && err == nil {
+		return configPath
+	}
+	return ""
+}
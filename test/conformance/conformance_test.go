@@ -0,0 +1,543 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"gopkg.in/yaml.v3"
+
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/config"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/git"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/interfaces"
+	"github.com/NadeeshaMedagama/branch_aware_ci/pkg/policy"
+	pb "github.com/NadeeshaMedagama/branch_aware_ci/proto/branchaware/v1"
+	"github.com/NadeeshaMedagama/branch_aware_ci/services/policy-engine/engine"
+	"github.com/NadeeshaMedagama/branch_aware_ci/services/policy-engine/handler"
+)
+
+// fixtures mirrors testdata/decisions.yaml
+type fixtures struct {
+	Suites []struct {
+		Name   string         `yaml:"name"`
+		Config *config.Config `yaml:"config"`
+		Cases  []struct {
+			Name   string `yaml:"name"`
+			Branch struct {
+				Name         string            `yaml:"name"`
+				Type         string            `yaml:"type"`
+				Protected    bool              `yaml:"protected"`
+				Metadata     map[string]string `yaml:"metadata"`
+				Target       string            `yaml:"target"`
+				ChangedFiles []string          `yaml:"changed_files"`
+				LinesChanged int               `yaml:"lines_changed"`
+				Deleted      bool              `yaml:"deleted"`
+			} `yaml:"branch"`
+			Expect struct {
+				Environment      string            `yaml:"environment"`
+				ShouldDeploy     bool              `yaml:"should_deploy"`
+				RequiresApproval bool              `yaml:"requires_approval"`
+				Actions          []string          `yaml:"actions"`
+				Variables        map[string]string `yaml:"variables"`
+				Warnings         []string          `yaml:"warnings"`
+				Findings         []string          `yaml:"findings"`
+				Approval         *struct {
+					Required  int      `yaml:"required"`
+					Reviewers []string `yaml:"reviewers"`
+				} `yaml:"approval"`
+				Risk *struct {
+					Score int    `yaml:"score"`
+					Level string `yaml:"level"`
+				} `yaml:"risk"`
+				Plan         [][]string `yaml:"plan"`
+				Environments []struct {
+					Environment      string            `yaml:"environment"`
+					ShouldDeploy     bool              `yaml:"should_deploy"`
+					RequiresApproval bool              `yaml:"requires_approval"`
+					Variables        map[string]string `yaml:"variables"`
+					Warnings         []string          `yaml:"warnings"`
+				} `yaml:"environments"`
+			} `yaml:"expect"`
+		} `yaml:"cases"`
+	} `yaml:"suites"`
+}
+
+// evaluator runs one decision through a particular entry point
+type evaluator func(t *testing.T, branchInfo *interfaces.BranchInfo, cfg *config.Config) *interfaces.Decision
+
+func TestConformance(t *testing.T) {
+	data, err := os.ReadFile("testdata/decisions.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read fixtures: %v", err)
+	}
+
+	var f fixtures
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		t.Fatalf("Failed to parse fixtures: %v", err)
+	}
+
+	evaluators := map[string]evaluator{
+		"cli":  evaluateCLI,
+		"http": newHTTPEvaluator(t),
+		"grpc": newGRPCEvaluator(t),
+	}
+
+	for _, suite := range f.Suites {
+		cfg := suite.Config
+		if cfg == nil {
+			cfg = config.DefaultConfig()
+		}
+
+		for _, tc := range suite.Cases {
+			branchInfo := &interfaces.BranchInfo{
+				Name:        tc.Branch.Name,
+				ShortName:   tc.Branch.Name,
+				Type:        tc.Branch.Type,
+				Metadata:    tc.Branch.Metadata,
+				IsProtected: tc.Branch.Protected,
+
+				TargetBranch: tc.Branch.Target,
+				ChangedFiles: tc.Branch.ChangedFiles,
+				LinesChanged: tc.Branch.LinesChanged,
+
+				Deleted: tc.Branch.Deleted,
+			}
+			if branchInfo.Metadata == nil {
+				branchInfo.Metadata = make(map[string]string)
+			}
+
+			for _, name := range []string{"cli", "http", "grpc"} {
+				t.Run(suite.Name+"/"+tc.Name+"/"+name, func(t *testing.T) {
+					d := evaluators[name](t, branchInfo, cfg)
+
+					if d.Environment != tc.Expect.Environment {
+						t.Errorf("Expected environment %s, got %s", tc.Expect.Environment, d.Environment)
+					}
+					if d.ShouldDeploy != tc.Expect.ShouldDeploy {
+						t.Errorf("Expected ShouldDeploy %v, got %v", tc.Expect.ShouldDeploy, d.ShouldDeploy)
+					}
+					if d.RequiresApproval != tc.Expect.RequiresApproval {
+						t.Errorf("Expected RequiresApproval %v, got %v", tc.Expect.RequiresApproval, d.RequiresApproval)
+					}
+					if !equalStrings(d.Actions, tc.Expect.Actions) {
+						t.Errorf("Expected actions %v, got %v", tc.Expect.Actions, d.Actions)
+					}
+					if !equalMaps(d.Variables, tc.Expect.Variables) {
+						t.Errorf("Expected variables %v, got %v", tc.Expect.Variables, d.Variables)
+					}
+					if !equalStrings(d.Warnings, tc.Expect.Warnings) {
+						t.Errorf("Expected warnings %q, got %q", tc.Expect.Warnings, d.Warnings)
+					}
+					if tc.Expect.Findings != nil {
+						var findings []string
+						for _, f := range d.Findings {
+							findings = append(findings, f.Severity+":"+f.Code)
+						}
+						if !equalStrings(findings, tc.Expect.Findings) {
+							t.Errorf("Expected findings %v, got %v", tc.Expect.Findings, findings)
+						}
+					}
+					if want := tc.Expect.Approval; want != nil {
+						var reviewers []string
+						if d.Approval != nil {
+							reviewers = append(append(reviewers, d.Approval.Teams...), d.Approval.Users...)
+						}
+						if d.Approval == nil || d.Approval.Required != want.Required || !equalStrings(reviewers, want.Reviewers) {
+							t.Errorf("Expected approval %+v, got %+v", *want, d.Approval)
+						}
+					}
+					if want := tc.Expect.Risk; want != nil {
+						if d.Risk == nil || d.Risk.Score != want.Score || d.Risk.Level != want.Level {
+							t.Errorf("Expected risk %+v, got %+v", *want, d.Risk)
+						}
+					}
+					if tc.Expect.Plan != nil {
+						var plan [][]string
+						for _, stage := range d.Plan {
+							var names []string
+							for _, a := range stage.Actions {
+								names = append(names, a.Name)
+							}
+							plan = append(plan, names)
+						}
+						if !reflect.DeepEqual(plan, tc.Expect.Plan) {
+							t.Errorf("Expected plan %v, got %v", tc.Expect.Plan, plan)
+						}
+					}
+					if len(d.Environments) != len(tc.Expect.Environments) {
+						t.Fatalf("Expected %d environment decisions, got %d", len(tc.Expect.Environments), len(d.Environments))
+					}
+					for i, want := range tc.Expect.Environments {
+						got := d.Environments[i]
+						if got.Environment != want.Environment || got.ShouldDeploy != want.ShouldDeploy ||
+							got.RequiresApproval != want.RequiresApproval {
+							t.Errorf("Expected environments[%d] %s (deploy %v, approval %v), got %s (deploy %v, approval %v)",
+								i, want.Environment, want.ShouldDeploy, want.RequiresApproval,
+								got.Environment, got.ShouldDeploy, got.RequiresApproval)
+						}
+						if !equalMaps(got.Variables, want.Variables) {
+							t.Errorf("Expected environments[%d] variables %v, got %v", i, want.Variables, got.Variables)
+						}
+						if !equalStrings(got.Warnings, want.Warnings) {
+							t.Errorf("Expected environments[%d] warnings %q, got %q", i, want.Warnings, got.Warnings)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func evaluateCLI(t *testing.T, branchInfo *interfaces.BranchInfo, cfg *config.Config) *interfaces.Decision {
+	e, err := policy.NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	decision, err := e.Evaluate(git.BranchInfoFromInterfaces(branchInfo))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	return decision.ToInterfaces()
+}
+
+func newHTTPEvaluator(t *testing.T) evaluator {
+	h := handler.NewHTTPHandler(engine.NewPolicyEngine())
+	server := httptest.NewServer(http.HandlerFunc(h.EvaluatePolicy))
+	t.Cleanup(server.Close)
+
+	return func(t *testing.T, branchInfo *interfaces.BranchInfo, cfg *config.Config) *interfaces.Decision {
+		body, err := json.Marshal(handler.EvaluatePolicyRequest{
+			BranchInfo: branchInfo,
+			Config:     cfg.ToInterfaces(),
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+
+		resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("HTTP request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var result handler.EvaluatePolicyResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK || result.Decision == nil {
+			t.Fatalf("Unexpected response: status %d, error %q", resp.StatusCode, result.Error)
+		}
+		return result.Decision
+	}
+}
+
+func newGRPCEvaluator(t *testing.T) evaluator {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterPolicyEngineServiceServer(server, handler.NewGRPCHandler(engine.NewPolicyEngine()))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewPolicyEngineServiceClient(conn)
+
+	return func(t *testing.T, branchInfo *interfaces.BranchInfo, cfg *config.Config) *interfaces.Decision {
+		resp, err := client.EvaluatePolicy(context.Background(), &pb.EvaluatePolicyRequest{
+			BranchInfo: &pb.BranchInfo{
+				Name:        branchInfo.Name,
+				ShortName:   branchInfo.ShortName,
+				Type:        branchInfo.Type,
+				Metadata:    branchInfo.Metadata,
+				IsProtected: branchInfo.IsProtected,
+
+				CommitSha:     branchInfo.CommitSHA,
+				CommitMessage: branchInfo.CommitMessage,
+				CommitAuthor:  branchInfo.CommitAuthor,
+				TargetBranch:  branchInfo.TargetBranch,
+				ChangedFiles:  branchInfo.ChangedFiles,
+				LinesChanged:  int32(branchInfo.LinesChanged),
+
+				Deleted: branchInfo.Deleted,
+			},
+			Config: configToProto(cfg),
+		})
+		if err != nil {
+			t.Fatalf("gRPC request failed: %v", err)
+		}
+
+		d := resp.GetDecision()
+		var environments []interfaces.EnvironmentDecision
+		for _, env := range d.GetEnvironments() {
+			environments = append(environments, interfaces.EnvironmentDecision{
+				Environment:      env.GetEnvironment(),
+				ShouldDeploy:     env.GetShouldDeploy(),
+				RequiresApproval: env.GetRequiresApproval(),
+				Approval:         approvalFromProto(env.GetApproval()),
+				Risk:             riskFromProto(env.GetRisk()),
+				Ephemeral:        env.GetEphemeral(),
+				Teardown:         env.GetTeardown(),
+				Actions:          env.GetActions(),
+				Variables:        env.GetVariables(),
+				Warnings:         env.GetWarnings(),
+				Findings:         findingsFromProto(env.GetFindings()),
+			})
+		}
+		return &interfaces.Decision{
+			BranchName:       d.GetBranchName(),
+			BranchType:       d.GetBranchType(),
+			CommitSHA:        d.GetCommitSha(),
+			Environment:      d.GetEnvironment(),
+			ShouldDeploy:     d.GetShouldDeploy(),
+			RequiresApproval: d.GetRequiresApproval(),
+			Actions:          d.GetActions(),
+			Variables:        d.GetVariables(),
+			Warnings:         d.GetWarnings(),
+			Findings:         findingsFromProto(d.GetFindings()),
+			Metadata:         d.GetMetadata(),
+			Environments:     environments,
+			Approval:         approvalFromProto(d.GetApproval()),
+			Risk:             riskFromProto(d.GetRisk()),
+			Plan:             planFromProto(d.GetPlan()),
+			Ephemeral:        d.GetEphemeral(),
+			Teardown:         d.GetTeardown(),
+		}
+	}
+}
+
+func approvalFromProto(a *pb.Approval) *interfaces.Approval {
+	if a == nil {
+		return nil
+	}
+	return &interfaces.Approval{
+		Required:          int(a.GetRequired()),
+		Teams:             a.GetTeams(),
+		Users:             a.GetUsers(),
+		AllowSelfApproval: a.GetAllowSelfApproval(),
+	}
+}
+
+func riskFromProto(r *pb.Risk) *interfaces.Risk {
+	if r == nil {
+		return nil
+	}
+	risk := &interfaces.Risk{Score: int(r.GetScore()), Level: r.GetLevel()}
+	for _, f := range r.GetFactors() {
+		risk.Factors = append(risk.Factors, interfaces.RiskFactor{Factor: f.GetFactor(), Points: int(f.GetPoints()), Reason: f.GetReason()})
+	}
+	return risk
+}
+
+func planFromProto(plan []*pb.PlanStage) []interfaces.PlanStage {
+	var result []interfaces.PlanStage
+	for _, stage := range plan {
+		var actions []interfaces.PlannedAction
+		for _, a := range stage.GetActions() {
+			actions = append(actions, interfaces.PlannedAction{Name: a.GetName(), Needs: a.GetNeeds(), Params: a.GetParams()})
+		}
+		result = append(result, interfaces.PlanStage{Actions: actions})
+	}
+	return result
+}
+
+func findingsFromProto(findings []*pb.Finding) []interfaces.Finding {
+	var result []interfaces.Finding
+	for _, f := range findings {
+		result = append(result, interfaces.Finding{Severity: f.GetSeverity(), Code: f.GetCode(), Message: f.GetMessage()})
+	}
+	return result
+}
+
+func configToProto(cfg *config.Config) *pb.Config {
+	c := &pb.Config{
+		Environments: make(map[string]*pb.Environment, len(cfg.Environments)),
+		Policies: &pb.PolicyConfig{
+			RequireTests:          cfg.Policies.RequireTests,
+			RequireCodeReview:     cfg.Policies.RequireCodeReview,
+			BlockedBranchPatterns: cfg.Policies.BlockedBranchPatterns,
+			AutoDeployBranches:    cfg.Policies.AutoDeployBranches,
+			Enforce:               cfg.Policies.Enforce,
+		},
+	}
+	for name, env := range cfg.Environments {
+		c.Environments[name] = &pb.Environment{
+			Name:             env.Name,
+			RequiresApproval: env.RequiresApproval,
+			AllowedBranches:  env.AllowedBranches,
+			Variables:        env.Variables,
+			NotifyOnDeploy:   env.NotifyOnDeploy,
+		}
+		if a := env.Approval; a != nil {
+			c.Environments[name].Approval = &pb.Approval{
+				Required:          int32(a.Required),
+				Teams:             a.Teams,
+				Users:             a.Users,
+				AllowSelfApproval: a.AllowSelfApproval,
+			}
+		}
+		if tmpl := env.Template; tmpl != nil {
+			c.Environments[name].Template = &pb.EnvironmentTemplate{
+				Name:      tmpl.Name,
+				MaxLength: int32(tmpl.MaxLength),
+				Url:       tmpl.URL,
+			}
+		}
+	}
+	for _, rule := range cfg.Policies.Rules {
+		c.Policies.Rules = append(c.Policies.Rules, &pb.Rule{
+			Name:            rule.Name,
+			When:            rule.When,
+			SetEnvironment:  rule.SetEnvironment,
+			AddActions:      rule.AddActions,
+			RemoveActions:   rule.RemoveActions,
+			RequireApproval: rule.RequireApproval,
+			Block:           rule.Block,
+			SetVariables:    rule.SetVariables,
+			Warn:            rule.Warn,
+			Severity:        rule.Severity,
+		})
+	}
+	for _, window := range cfg.FreezeWindows {
+		c.FreezeWindows = append(c.FreezeWindows, &pb.FreezeWindow{
+			Name:           window.Name,
+			Environments:   window.Environments,
+			Cron:           window.Cron,
+			Duration:       window.Duration,
+			Start:          window.Start,
+			End:            window.End,
+			Timezone:       window.Timezone,
+			ExemptBranches: window.ExemptBranches,
+		})
+	}
+	if len(cfg.Promotion.Chains) > 0 || cfg.Promotion.StateFile != "" {
+		c.Promotion = &pb.PromotionConfig{StateFile: cfg.Promotion.StateFile}
+		for _, chain := range cfg.Promotion.Chains {
+			c.Promotion.Chains = append(c.Promotion.Chains, &pb.PromotionChain{
+				Name:         chain.Name,
+				Environments: chain.Environments,
+				OnIneligible: chain.OnIneligible,
+			})
+		}
+	}
+	if r := cfg.Risk; r != nil {
+		c.Risk = &pb.RiskConfig{
+			BranchTypes:  int32Map(r.BranchTypes),
+			Environments: int32Map(r.Environments),
+			Changes: &pb.RiskChanges{
+				PerFile:         int32(r.Changes.PerFile),
+				PerHundredLines: int32(r.Changes.PerHundredLines),
+				Max:             int32(r.Changes.Max),
+			},
+		}
+		for _, path := range r.SensitivePaths {
+			c.Risk.SensitivePaths = append(c.Risk.SensitivePaths, &pb.RiskPath{Pattern: path.Pattern, Weight: int32(path.Weight)})
+		}
+		if o := r.OffHours; o != nil {
+			c.Risk.OffHours = &pb.RiskOffHours{
+				Start:    int32(o.Start),
+				End:      int32(o.End),
+				Weekends: o.Weekends,
+				Timezone: o.Timezone,
+				Weight:   int32(o.Weight),
+			}
+		}
+		for _, threshold := range r.Thresholds {
+			c.Risk.Thresholds = append(c.Risk.Thresholds, &pb.RiskThreshold{
+				Name:            threshold.Name,
+				Score:           int32(threshold.Score),
+				RequireApproval: threshold.RequireApproval,
+				AddActions:      threshold.AddActions,
+			})
+		}
+	}
+	if len(cfg.Actions) > 0 {
+		c.Actions = make(map[string]*pb.Action, len(cfg.Actions))
+		for name, action := range cfg.Actions {
+			c.Actions[name] = &pb.Action{Description: action.Description, Needs: action.Needs, Params: action.Params}
+		}
+	}
+	for _, mapping := range cfg.BranchMappings {
+		c.BranchMappings = append(c.BranchMappings, &pb.BranchMapping{
+			Pattern:      mapping.Pattern,
+			Environment:  mapping.Environment,
+			Environments: mapping.Environments,
+			Actions:      mapping.Actions,
+			Priority:     int32(mapping.Priority),
+		})
+	}
+	return c
+}
+
+func int32Map(m map[string]int) map[string]int32 {
+	result := make(map[string]int32, len(m))
+	for k, v := range m {
+		result[k] = int32(v)
+	}
+	return result
+}
+
+// equalStrings compares slices, treating nil and empty as equal
+func equalStrings(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// equalMaps compares maps, treating nil and empty as equal
+func equalMaps(a, b map[string]string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func TestHTTPExplain(t *testing.T) {
+	h := handler.NewHTTPHandler(engine.NewPolicyEngine())
+	server := httptest.NewServer(http.HandlerFunc(h.EvaluatePolicy))
+	defer server.Close()
+
+	body, _ := json.Marshal(handler.EvaluatePolicyRequest{
+		BranchInfo: &interfaces.BranchInfo{ShortName: "feature/x", Type: "feature", Metadata: map[string]string{}},
+		Config:     config.DefaultConfig().ToInterfaces(),
+	})
+
+	for _, explain := range []bool{false, true} {
+		url := server.URL
+		if explain {
+			url += "?explain=true"
+		}
+		resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("HTTP request failed: %v", err)
+		}
+		var result handler.EvaluatePolicyResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		resp.Body.Close()
+
+		if explain && (result.Decision.Trace == nil || result.Decision.Trace.Winner == nil) {
+			t.Errorf("Expected a trace with a winner, got %+v", result.Decision.Trace)
+		}
+		if !explain && result.Decision.Trace != nil {
+			t.Error("Expected no trace without explain")
+		}
+	}
+}